@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/xh3b4sd/logger"
 	"github.com/xh3b4sd/tracer"
@@ -8,6 +9,7 @@ import (
 	"github.com/xh3b4sd/pag/cmd/completion"
 	"github.com/xh3b4sd/pag/cmd/generate"
 	"github.com/xh3b4sd/pag/cmd/version"
+	"github.com/xh3b4sd/pag/pkg/executor"
 	"github.com/xh3b4sd/pag/pkg/project"
 )
 
@@ -17,10 +19,18 @@ var (
 )
 
 type Config struct {
-	Logger logger.Interface
+	Executor   executor.Interface
+	FileSystem afero.Fs
+	Logger     logger.Interface
 }
 
 func New(config Config) (*cobra.Command, error) {
+	if config.Executor == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Executor must not be empty", config)
+	}
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
 	if config.Logger == nil {
 		return nil, tracer.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
@@ -42,7 +52,9 @@ func New(config Config) (*cobra.Command, error) {
 	var generateCmd *cobra.Command
 	{
 		c := generate.Config{
-			Logger: config.Logger,
+			Executor:   config.Executor,
+			FileSystem: config.FileSystem,
+			Logger:     config.Logger,
 		}
 
 		generateCmd, err = generate.New(c)
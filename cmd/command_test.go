@@ -0,0 +1,510 @@
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/logger/fake"
+
+	executor "github.com/xh3b4sd/pag/pkg/executor/fake"
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_Command_Generate tests the complete generation flow of the generate
+// commands, from loading the project configuration to installing the
+// generated files into their destinations. Protoc is emulated by the fake
+// executor, so that no gRPC tooling needs to be installed. Projects are
+// generated from outside of their directory, which is referenced as $DIR
+// within arguments. The golden files contain the executed commands followed by
+// all files of the project after the generation.
+//
+//	go test ./cmd -run Test_Command_Generate -update
+func Test_Command_Generate(t *testing.T) {
+	testCases := []struct {
+		arg []string
+		cwd bool
+		rep int
+		src map[string]string
+	}{
+		// Case 0 ensures that all targets of the project configuration are
+		// generated.
+		{
+			arg: []string{"generate", "all", "--config", "$DIR/pag.yaml"},
+			src: map[string]string{
+				"pag.yaml": `targets:
+  - language: golang
+    destination: pkg/
+  - language: typescript
+    destination: src/
+`,
+				"pbf/user/api.proto":    "syntax = \"proto3\";\n\nservice API {}\n",
+				"pbf/user/create.proto": "syntax = \"proto3\";\n\nmessage CreateI {}\n",
+				"pbf/post/delete.proto": "syntax = \"proto3\";\n\nmessage DeleteI {}\n",
+			},
+		},
+		// Case 1 ensures that a single target is generated based on command
+		// line flags without any project configuration.
+		{
+			arg: []string{"generate", "golang", "--source", "$DIR/pbf/", "--destination", "$DIR/gen/", "--services", "grpc,connect"},
+			src: map[string]string{
+				"pbf/user/api.proto": "syntax = \"proto3\";\n\nservice API {}\n",
+			},
+		},
+		// Case 2 ensures that excluded schema files are not generated.
+		{
+			arg: []string{"generate", "golang", "--source", "$DIR", "--destination", "$DIR/pkg/", "--exclude", "$DIR/pbf/post"},
+			src: map[string]string{
+				"pbf/user/api.proto":    "syntax = \"proto3\";\n\nservice API {}\n",
+				"pbf/post/delete.proto": "syntax = \"proto3\";\n\nmessage DeleteI {}\n",
+			},
+		},
+		// Case 3 ensures that repeated generation restores the generated code
+		// from the cache without executing any command.
+		{
+			arg: []string{"generate", "golang", "--source", "$DIR", "--destination", "$DIR/pkg/", "--cache", "$DIR/.pag/cache"},
+			rep: 1,
+			src: map[string]string{
+				"pbf/user/api.proto": "syntax = \"proto3\";\n\nservice API {}\n",
+			},
+		},
+		// Case 4 ensures that all languages are generated with their default
+		// flags without any project configuration.
+		{
+			arg: []string{"generate", "all"},
+			cwd: true,
+			src: map[string]string{
+				"pbf/user/api.proto": "syntax = \"proto3\";\n\nservice API {}\n",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			dir := t.TempDir()
+			for p, s := range tc.src {
+				mustWriteFile(filepath.Join(dir, p), s)
+			}
+
+			p, err := filepath.Abs(filepath.Join("testdata/generate", fileName(i)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Commands may be executed within the project directory, so that
+			// default flags refer to the project, just like they would when
+			// running pag in a real project.
+			if tc.cwd {
+				mustChdir(t, dir)
+			}
+
+			var arg []string
+			for _, a := range tc.arg {
+				arg = append(arg, strings.ReplaceAll(a, "$DIR", dir))
+			}
+
+			// Only the commands of the last execution are part of the golden
+			// file.
+			var x *executor.Executor
+			for j := 0; j <= tc.rep; j++ {
+				x = executor.NewProtoc(afero.NewOsFs())
+
+				c, err := New(Config{Executor: x, FileSystem: afero.NewOsFs(), Logger: fake.New()})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				c.SetArgs(arg)
+
+				err = c.Execute()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var b bytes.Buffer
+
+			for _, c := range x.Commands() {
+				b.WriteString(c.String() + "\n")
+			}
+
+			b.WriteString("\n")
+
+			for _, p := range mustWalk(dir) {
+				b.WriteString("==> " + p + "\n")
+
+				// Cache entries contain the generated files of the temporary
+				// destinations, which are already part of the golden file.
+				if strings.HasPrefix(p, ".pag/cache/") {
+					continue
+				}
+
+				f, err := ioutil.ReadFile(filepath.Join(dir, p))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				b.Write(f)
+			}
+
+			// Commands are executed within temporary destinations first,
+			// which are random and therefore replaced with a stable name, the
+			// same way as the project directory.
+			actual := bytes.ReplaceAll(b.Bytes(), []byte(dir), []byte("$DIR"))
+			actual = bytes.ReplaceAll(actual, []byte(strings.TrimPrefix(dir, "/")), []byte("$DIR"))
+			actual = regexp.MustCompile(`[^\s=:]*/pag-generate-[0-9]+`).ReplaceAll(actual, []byte("tmp"))
+
+			// Cache keys cover the installed protoc plugins, which is why
+			// cache entries are named differently on every machine.
+			actual = regexp.MustCompile(`[0-9a-f]{2}/[0-9a-f]{64}\.json`).ReplaceAll(actual, []byte("key.json"))
+
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Command_Generate_Archive tests the generation of code into archives.
+// The tests here run entirely in memory, using the fake executor as stand-in
+// for protoc, and ensure that archives contain all generated files, while no
+// destination is touched. The golden files contain the executed commands
+// followed by the name and content of every archived file.
+//
+//	go test ./cmd -run Test_Command_Generate_Archive -update
+func Test_Command_Generate_Archive(t *testing.T) {
+	testCases := []struct {
+		arg []string
+		src map[string]string
+	}{
+		// Case 0 ensures that golang code is archived as zip archive.
+		{
+			arg: []string{"generate", "golang", "--archive", "out/code.zip"},
+			src: map[string]string{
+				"pbf/user/api.proto":    "syntax = \"proto3\";\n\nservice API {}\n",
+				"pbf/post/delete.proto": "syntax = \"proto3\";\n\nmessage DeleteI {}\n",
+			},
+		},
+		// Case 1 ensures that typescript code is archived as tar.gz archive.
+		{
+			arg: []string{"generate", "typescript", "--archive", "out/code.tar.gz"},
+			src: map[string]string{
+				"pbf/user/api.proto": "syntax = \"proto3\";\n\nservice API {}\n",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			// The in-memory file system is rooted at "/", so that relative
+			// paths of the project and absolute paths of temporary
+			// directories resolve within the same tree.
+			fs := afero.NewBasePathFs(afero.NewMemMapFs(), "/")
+			for p, s := range tc.src {
+				err := afero.WriteFile(fs, p, []byte(s), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			x := executor.NewProtoc(fs)
+
+			c, err := New(Config{Executor: x, FileSystem: fs, Logger: fake.New()})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c.SetArgs(tc.arg)
+
+			err = c.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, d := range []string{"pkg", "src"} {
+				ok, err := afero.DirExists(fs, d)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if ok {
+					t.Fatalf("expected destination %s not to exist", d)
+				}
+			}
+
+			var b bytes.Buffer
+
+			for _, c := range x.Commands() {
+				b.WriteString(c.String() + "\n")
+			}
+
+			b.WriteString("\n")
+
+			a, err := afero.ReadFile(fs, tc.arg[3])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, f := range mustUnarchive(tc.arg[3], a) {
+				b.WriteString("==> " + f[0] + "\n")
+				b.WriteString(f[1])
+			}
+
+			actual := regexp.MustCompile(`[^\s=:]*/pag-generate-[0-9]+`).ReplaceAll(b.Bytes(), []byte("tmp"))
+
+			p := filepath.Join("testdata/archive", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Command_Generate_Watch tests the watch mode of the generate commands.
+// The tests here ensure that changing a single schema only executes the
+// commands of that schema again, even without a configured cache.
+func Test_Command_Generate_Watch(t *testing.T) {
+	fs := afero.NewBasePathFs(afero.NewMemMapFs(), "/")
+
+	mustWriteMemFile(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\nservice API {}\n")
+	mustWriteMemFile(fs, "pbf/post/delete.proto", "syntax = \"proto3\";\n\nmessage DeleteI {}\n")
+
+	x := executor.NewProtoc(fs)
+
+	c, err := New(Config{Executor: x, FileSystem: fs, Logger: fake.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.SetArgs([]string{"generate", "golang", "--watch"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	res := make(chan error, 1)
+	go func() {
+		res <- c.ExecuteContext(ctx)
+	}()
+
+	mustWaitCommands(t, x, 4)
+
+	mustWriteMemFile(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\nservice API { rpc Search(SearchI) returns (SearchO); }\n")
+
+	l := mustWaitCommands(t, x, 6)
+
+	cancel()
+
+	err = <-res
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []string
+	for _, c := range l {
+		if strings.Contains(c.String(), "pbf/post/") {
+			actual = append(actual, c.Arguments[1])
+		}
+	}
+
+	expected := []string{
+		"--go-grpc_out=tmp/cmd/0/1/pbf/post/",
+		"--go_out=tmp/cmd/0/0/pbf/post/",
+	}
+
+	for i := range actual {
+		actual[i] = regexp.MustCompile(`[^\s=:]*/pag-generate-[0-9]+`).ReplaceAllString(actual[i], "tmp")
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expected, actual))
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
+
+// mustUnarchive returns the name and content of every file within the given
+// tar.gz or zip archive, in the order of the archive.
+func mustUnarchive(p string, b []byte) [][2]string {
+	var l [][2]string
+
+	if strings.HasSuffix(p, ".zip") {
+		r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			panic(err)
+		}
+
+		for _, f := range r.File {
+			o, err := f.Open()
+			if err != nil {
+				panic(err)
+			}
+
+			c, err := ioutil.ReadAll(o)
+			if err != nil {
+				panic(err)
+			}
+
+			l = append(l, [2]string{f.Name, string(c)})
+		}
+
+		return l
+	}
+
+	g, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		panic(err)
+	}
+
+	r := tar.NewReader(g)
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			panic(err)
+		}
+
+		c, err := ioutil.ReadAll(r)
+		if err != nil {
+			panic(err)
+		}
+
+		l = append(l, [2]string{h.Name, string(c)})
+	}
+
+	return l
+}
+
+// mustWaitCommands waits until the given executor executed at least the given
+// number of commands and returns all of them.
+func mustWaitCommands(t *testing.T, x *executor.Executor, n int) []generate.Command {
+	d := time.Now().Add(10 * time.Second)
+
+	for time.Now().Before(d) {
+		l := x.Commands()
+		if len(l) >= n {
+			return l
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("expected %d commands to be executed", n)
+
+	return nil
+}
+
+// mustWalk returns the paths of all files within the given directory relative
+// to the given directory.
+func mustWalk(dir string) []string {
+	var l []string
+
+	err := filepath.Walk(dir, func(p string, i os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		if i.IsDir() {
+			return nil
+		}
+
+		l = append(l, rel)
+
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	sort.Strings(l)
+
+	return l
+}
+
+// mustChdir changes the working directory to dir until the given test ends.
+func mustChdir(t *testing.T, dir string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		err := os.Chdir(wd)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func mustWriteFile(p string, s string) {
+	err := os.MkdirAll(filepath.Dir(p), os.ModePerm)
+	if err != nil {
+		panic(err)
+	}
+
+	err = ioutil.WriteFile(p, []byte(s), 0600)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustWriteMemFile(fs afero.Fs, p string, s string) {
+	err := afero.WriteFile(fs, p, []byte(s), 0600)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,71 @@
+package all
+
+import (
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/xh3b4sd/logger"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/engine"
+	"github.com/xh3b4sd/pag/pkg/schema"
+)
+
+const (
+	name  = "all"
+	short = "Generate code for all targets of the project configuration."
+	long  = `Generate code for all targets of the project configuration. All targets
+are generated in one pass, sharing a single scan of their gRPC api schema
+definitions. The project configuration file is discovered from the working
+directory upward, unless it is given via -c/--config. Without any project
+configuration file, code is generated for all supported languages using
+their default flags.
+
+    source: .
+    targets:
+      - language: golang
+        destination: ./pkg/
+      - language: typescript
+        destination: ./src/
+`
+)
+
+type Config struct {
+	Engine     *engine.Engine
+	FileSystem afero.Fs
+	Logger     logger.Interface
+	Schemas    *schema.Cache
+}
+
+func New(config Config) (*cobra.Command, error) {
+	if config.Engine == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Engine must not be empty", config)
+	}
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Schemas == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Schemas must not be empty", config)
+	}
+
+	var c *cobra.Command
+	{
+		r := &runner{
+			engine:     config.Engine,
+			fileSystem: config.FileSystem,
+			logger:     config.Logger,
+			schemas:    config.Schemas,
+		}
+
+		c = &cobra.Command{
+			Use:   name,
+			Short: short,
+			Long:  long,
+			RunE:  r.Run,
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,146 @@
+package all
+
+import (
+	"context"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/xh3b4sd/logger"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/cmd/generate/csharp"
+	"github.com/xh3b4sd/pag/cmd/generate/dart"
+	"github.com/xh3b4sd/pag/cmd/generate/golang"
+	"github.com/xh3b4sd/pag/cmd/generate/java"
+	"github.com/xh3b4sd/pag/cmd/generate/python"
+	"github.com/xh3b4sd/pag/cmd/generate/rust"
+	"github.com/xh3b4sd/pag/cmd/generate/swift"
+	"github.com/xh3b4sd/pag/cmd/generate/typescript"
+	"github.com/xh3b4sd/pag/pkg/config"
+	"github.com/xh3b4sd/pag/pkg/engine"
+	"github.com/xh3b4sd/pag/pkg/schema"
+)
+
+// languages are all languages the all command can generate code for. Without
+// any project configuration file, code is generated for every one of them
+// using their default flags.
+var languages = []string{
+	"csharp",
+	"dart",
+	"golang",
+	"java",
+	"python",
+	"rust",
+	"swift",
+	"typescript",
+}
+
+type runner struct {
+	engine     *engine.Engine
+	fileSystem afero.Fs
+	logger     logger.Interface
+	schemas    *schema.Cache
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	err := r.run(ctx, cmd, args)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var c config.Config
+	{
+		p, err := cmd.Flags().GetString("config")
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		c, err = config.Discover(r.fileSystem, p)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	targets := c.Targets
+	if c.Path == "" {
+		for _, l := range languages {
+			targets = append(targets, config.Target{Language: l})
+		}
+	} else if len(targets) == 0 {
+		return tracer.Maskf(invalidConfigError, "%s must declare at least one target", c.Path)
+	}
+
+	// Every target is handled by a dedicated instance of its language
+	// specific sub command, which adds the target's generator to the shared
+	// engine. The engine executes all generators in one pass once all
+	// targets are prepared, so that invalid targets are detected before
+	// generating any code.
+	var cmds []*cobra.Command
+	for _, t := range targets {
+		var s *cobra.Command
+		{
+			s, err = r.command(t.Language)
+			if err != nil {
+				return tracer.Mask(err)
+			}
+		}
+
+		err = c.Apply(s, t)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		cmds = append(cmds, s)
+	}
+
+	for _, s := range cmds {
+		err := s.RunE(s, nil)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// command returns a new sub command for the given language. All sub commands
+// share the same schemas, so that every source is only scanned once.
+func (r *runner) command(language string) (*cobra.Command, error) {
+	var err error
+
+	var c *cobra.Command
+	switch language {
+	case "csharp":
+		c, err = csharp.New(csharp.Config{Engine: r.engine, FileSystem: r.fileSystem, Logger: r.logger, Schemas: r.schemas})
+	case "dart":
+		c, err = dart.New(dart.Config{Engine: r.engine, FileSystem: r.fileSystem, Logger: r.logger, Schemas: r.schemas})
+	case "golang":
+		c, err = golang.New(golang.Config{Engine: r.engine, FileSystem: r.fileSystem, Logger: r.logger, Schemas: r.schemas})
+	case "java":
+		c, err = java.New(java.Config{Engine: r.engine, FileSystem: r.fileSystem, Logger: r.logger, Schemas: r.schemas})
+	case "python":
+		c, err = python.New(python.Config{Engine: r.engine, FileSystem: r.fileSystem, Logger: r.logger, Schemas: r.schemas})
+	case "rust":
+		c, err = rust.New(rust.Config{Engine: r.engine, FileSystem: r.fileSystem, Logger: r.logger, Schemas: r.schemas})
+	case "swift":
+		c, err = swift.New(swift.Config{Engine: r.engine, FileSystem: r.fileSystem, Logger: r.logger, Schemas: r.schemas})
+	case "typescript":
+		c, err = typescript.New(typescript.Config{Engine: r.engine, FileSystem: r.fileSystem, Logger: r.logger, Schemas: r.schemas})
+	default:
+		return nil, tracer.Maskf(invalidConfigError, "%s must not declare unknown language %s", config.File, language)
+	}
+
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	return c, nil
+}
@@ -1,12 +1,25 @@
 package generate
 
 import (
+	"os"
+
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/xh3b4sd/logger"
 	"github.com/xh3b4sd/tracer"
 
+	"github.com/xh3b4sd/pag/cmd/generate/all"
+	"github.com/xh3b4sd/pag/cmd/generate/csharp"
+	"github.com/xh3b4sd/pag/cmd/generate/dart"
 	"github.com/xh3b4sd/pag/cmd/generate/golang"
+	"github.com/xh3b4sd/pag/cmd/generate/java"
+	"github.com/xh3b4sd/pag/cmd/generate/python"
+	"github.com/xh3b4sd/pag/cmd/generate/rust"
+	"github.com/xh3b4sd/pag/cmd/generate/swift"
 	"github.com/xh3b4sd/pag/cmd/generate/typescript"
+	"github.com/xh3b4sd/pag/pkg/engine"
+	"github.com/xh3b4sd/pag/pkg/executor"
+	"github.com/xh3b4sd/pag/pkg/schema"
 )
 
 const (
@@ -15,20 +28,100 @@ const (
 )
 
 type Config struct {
-	Logger logger.Interface
+	Executor executor.Interface
+	// FileSystem is where schemas and configurations are read from and where
+	// generated code is written to. Note that the executor has to write to
+	// the same file system, e.g. afero.NewOsFs() for protoc processes.
+	FileSystem afero.Fs
+	Logger     logger.Interface
 }
 
 func New(config Config) (*cobra.Command, error) {
+	if config.Executor == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Executor must not be empty", config)
+	}
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
 	if config.Logger == nil {
 		return nil, tracer.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
 
 	var err error
 
+	// All generators share the same schemas so that every source is only
+	// scanned once, even if multiple generators are executed in one pass.
+	schemas := schema.NewCache()
+
+	// All generate sub commands add their generators to the same engine,
+	// which executes them once the sub command finished.
+	var e *engine.Engine
+	{
+		c := engine.Config{
+			Executor:   config.Executor,
+			FileSystem: config.FileSystem,
+			Logger:     config.Logger,
+			Output:     os.Stdout,
+		}
+
+		e, err = engine.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	var allCmd *cobra.Command
+	{
+		c := all.Config{
+			Engine:     e,
+			FileSystem: config.FileSystem,
+			Logger:     config.Logger,
+			Schemas:    schemas,
+		}
+
+		allCmd, err = all.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	var csharpCmd *cobra.Command
+	{
+		c := csharp.Config{
+			Engine:     e,
+			FileSystem: config.FileSystem,
+			Logger:     config.Logger,
+			Schemas:    schemas,
+		}
+
+		csharpCmd, err = csharp.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	var dartCmd *cobra.Command
+	{
+		c := dart.Config{
+			Engine:     e,
+			FileSystem: config.FileSystem,
+			Logger:     config.Logger,
+			Schemas:    schemas,
+		}
+
+		dartCmd, err = dart.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
 	var golangCmd *cobra.Command
 	{
 		c := golang.Config{
-			Logger: config.Logger,
+			Engine:     e,
+			FileSystem: config.FileSystem,
+			Logger:     config.Logger,
+			Schemas:    schemas,
 		}
 
 		golangCmd, err = golang.New(c)
@@ -37,10 +130,73 @@ func New(config Config) (*cobra.Command, error) {
 		}
 	}
 
+	var javaCmd *cobra.Command
+	{
+		c := java.Config{
+			Engine:     e,
+			FileSystem: config.FileSystem,
+			Logger:     config.Logger,
+			Schemas:    schemas,
+		}
+
+		javaCmd, err = java.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	var pythonCmd *cobra.Command
+	{
+		c := python.Config{
+			Engine:     e,
+			FileSystem: config.FileSystem,
+			Logger:     config.Logger,
+			Schemas:    schemas,
+		}
+
+		pythonCmd, err = python.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	var rustCmd *cobra.Command
+	{
+		c := rust.Config{
+			Engine:     e,
+			FileSystem: config.FileSystem,
+			Logger:     config.Logger,
+			Schemas:    schemas,
+		}
+
+		rustCmd, err = rust.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	var swiftCmd *cobra.Command
+	{
+		c := swift.Config{
+			Engine:     e,
+			FileSystem: config.FileSystem,
+			Logger:     config.Logger,
+			Schemas:    schemas,
+		}
+
+		swiftCmd, err = swift.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
 	var typescriptCmd *cobra.Command
 	{
 		c := typescript.Config{
-			Logger: config.Logger,
+			Engine:     e,
+			FileSystem: config.FileSystem,
+			Logger:     config.Logger,
+			Schemas:    schemas,
 		}
 
 		typescriptCmd, err = typescript.New(c)
@@ -51,18 +207,37 @@ func New(config Config) (*cobra.Command, error) {
 
 	var c *cobra.Command
 	{
+		f := &flag{}
+
 		r := &runner{
-			logger: config.Logger,
+			all:        allCmd,
+			engine:     e,
+			fileSystem: config.FileSystem,
+			flag:       f,
+			logger:     config.Logger,
+			output:     os.Stdout,
+			schemas:    schemas,
 		}
 
 		c = &cobra.Command{
-			Use:   name,
-			Short: description,
-			Long:  description,
-			RunE:  r.Run,
+			Use:                name,
+			Short:              description,
+			Long:               description,
+			PersistentPreRunE:  r.PreRun,
+			RunE:               r.Run,
+			PersistentPostRunE: r.PostRun,
 		}
 
+		f.Init(c)
+
+		c.AddCommand(allCmd)
+		c.AddCommand(csharpCmd)
+		c.AddCommand(dartCmd)
 		c.AddCommand(golangCmd)
+		c.AddCommand(javaCmd)
+		c.AddCommand(pythonCmd)
+		c.AddCommand(rustCmd)
+		c.AddCommand(swiftCmd)
 		c.AddCommand(typescriptCmd)
 	}
 
@@ -0,0 +1,46 @@
+package csharp
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/generate/csharp"
+)
+
+type flag struct {
+	Destination string
+	Exclude     []string
+	Grpc        string
+	Project     string
+	Protobuf    string
+	Source      string
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.Destination, "destination", "d", "./gen/", "Directory to put the generated csharp code into.")
+	cmd.Flags().StringSliceVarP(&f.Exclude, "exclude", "e", nil, "Files and directories to ignore when looking for the gRPC api schema definitions.")
+	cmd.Flags().StringVar(&f.Grpc, "grpc-version", csharp.GrpcVersion, "Exact version of the Grpc.Core.Api package referenced by the generated .csproj file.")
+	cmd.Flags().StringVarP(&f.Project, "project", "p", "Api", "Name of the generated .csproj file and root namespace of the generated resource classes.")
+	cmd.Flags().StringVar(&f.Protobuf, "protobuf-version", csharp.ProtobufVersion, "Exact version of the Google.Protobuf package referenced by the generated .csproj file.")
+	cmd.Flags().StringVarP(&f.Source, "source", "s", ".", "Directory to look for the gRPC api schema definitions.")
+}
+
+func (f *flag) Validate() error {
+	if f.Destination == "" {
+		return tracer.Maskf(invalidFlagError, "-d/--destination must not be empty")
+	}
+	if f.Grpc == "" {
+		return tracer.Maskf(invalidFlagError, "--grpc-version must not be empty")
+	}
+	if f.Project == "" {
+		return tracer.Maskf(invalidFlagError, "-p/--project must not be empty")
+	}
+	if f.Protobuf == "" {
+		return tracer.Maskf(invalidFlagError, "--protobuf-version must not be empty")
+	}
+	if f.Source == "" {
+		return tracer.Maskf(invalidFlagError, "-s/--source must not be empty")
+	}
+
+	return nil
+}
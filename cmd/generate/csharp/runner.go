@@ -0,0 +1,88 @@
+package csharp
+
+import (
+	"context"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/xh3b4sd/logger"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/engine"
+	"github.com/xh3b4sd/pag/pkg/generate"
+	"github.com/xh3b4sd/pag/pkg/generate/csharp"
+	"github.com/xh3b4sd/pag/pkg/schema"
+)
+
+type runner struct {
+	engine     *engine.Engine
+	fileSystem afero.Fs
+	flag       *flag
+	logger     logger.Interface
+	schemas    *schema.Cache
+}
+
+func (r *runner) Run(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	err := r.flag.Validate()
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	err = r.run(ctx, cmd, args)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	var s *schema.Schema
+	{
+		c := schema.Config{
+			FileSystem: r.fileSystem,
+
+			Exclude: r.flag.Exclude,
+			Source:  r.flag.Source,
+		}
+
+		s, err = r.schemas.Schema(c)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	// The generator is created for every destination the engine asks for,
+	// e.g. temporary directories when checking the generated code.
+	t := engine.Target{
+		Destination: r.flag.Destination,
+		Generator: func(dst string) (generate.Interface, error) {
+			c := csharp.Config{
+				FileSystem: r.fileSystem,
+				Schema:     s,
+
+				Destination: dst,
+				Exclude:     r.flag.Exclude,
+				Grpc:        r.flag.Grpc,
+				Project:     r.flag.Project,
+				Protobuf:    r.flag.Protobuf,
+				Source:      r.flag.Source,
+			}
+
+			g, err := csharp.New(c)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			return g, nil
+		},
+	}
+
+	r.engine.Add(t)
+
+	return nil
+}
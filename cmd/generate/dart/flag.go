@@ -0,0 +1,29 @@
+package dart
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/xh3b4sd/tracer"
+)
+
+type flag struct {
+	Destination string
+	Exclude     []string
+	Source      string
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.Destination, "destination", "d", "./lib/", "Directory to put the generated dart code into.")
+	cmd.Flags().StringSliceVarP(&f.Exclude, "exclude", "e", nil, "Files and directories to ignore when looking for the gRPC api schema definitions.")
+	cmd.Flags().StringVarP(&f.Source, "source", "s", ".", "Directory to look for the gRPC api schema definitions.")
+}
+
+func (f *flag) Validate() error {
+	if f.Destination == "" {
+		return tracer.Maskf(invalidFlagError, "-d/--destination must not be empty")
+	}
+	if f.Source == "" {
+		return tracer.Maskf(invalidFlagError, "-s/--source must not be empty")
+	}
+
+	return nil
+}
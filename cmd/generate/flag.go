@@ -0,0 +1,75 @@
+package generate
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/archive"
+	"github.com/xh3b4sd/pag/pkg/engine"
+)
+
+type flag struct {
+	Archive string
+	Cache   string
+	Check   bool
+	Config  string
+	DryRun  bool
+	Format  string
+	Jobs    int
+	Plugins []string
+	Prune   string
+	Timeout time.Duration
+	Watch   bool
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&f.Archive, "archive", "", "Write the generated code into the given .tar.gz, .tgz or .zip archive instead of the destinations.")
+	cmd.PersistentFlags().StringVar(&f.Cache, "cache", "", "Directory to cache generated code in, e.g. .pag/cache, so that protoc only runs for changed schemas. Disabled if empty.")
+	cmd.PersistentFlags().BoolVar(&f.Check, "check", false, "Fail and print a diff if the generated code differs from the code in the destination.")
+	cmd.PersistentFlags().StringVarP(&f.Config, "config", "c", "", "Project configuration file, discovered from the working directory upward if empty.")
+	cmd.PersistentFlags().BoolVar(&f.DryRun, "dry-run", false, "Print the commands, files and staged schema files of the generation plan without executing or writing anything.")
+	cmd.PersistentFlags().StringVar(&f.Format, "format", engine.FormatText, "Format of the printed generation plan and protoc diagnostics, either text or json.")
+	cmd.PersistentFlags().IntVarP(&f.Jobs, "jobs", "j", runtime.NumCPU(), "Maximum number of protoc commands executed concurrently.")
+	cmd.PersistentFlags().StringSliceVar(&f.Plugins, "plugins", nil, "Directories to look for protoc plugins before looking into the PATH.")
+	cmd.PersistentFlags().StringVar(&f.Prune, "prune", "", "Handle files generated before but not generated anymore, either list or delete.")
+	cmd.PersistentFlags().DurationVar(&f.Timeout, "timeout", 0, "Maximum duration of every protoc command, e.g. 2m, without any limit if 0.")
+	cmd.PersistentFlags().BoolVarP(&f.Watch, "watch", "w", false, "Keep running and generate code again whenever protocol buffer files change.")
+	// Using --prune without value only lists the stale files, so that they
+	// can be reviewed before actually deleting them using --prune=delete.
+	cmd.PersistentFlags().Lookup("prune").NoOptDefVal = engine.PruneList
+}
+
+func (f *flag) Validate() error {
+	if f.Archive != "" && archive.Format(f.Archive) == "" {
+		return tracer.Maskf(invalidFlagError, "--archive must end with .tar.gz, .tgz or .zip")
+	}
+	if f.Archive != "" && (f.Check || f.DryRun || f.Prune != "") {
+		return tracer.Maskf(invalidFlagError, "--archive must not be used together with --check, --dry-run or --prune")
+	}
+	if f.Check && f.DryRun {
+		return tracer.Maskf(invalidFlagError, "--check and --dry-run must not be used together")
+	}
+	if f.Format != engine.FormatJSON && f.Format != engine.FormatText {
+		return tracer.Maskf(invalidFlagError, "--format must be %s or %s", engine.FormatJSON, engine.FormatText)
+	}
+	if f.Jobs < 1 {
+		return tracer.Maskf(invalidFlagError, "--jobs must be greater than 0")
+	}
+	if f.Watch && (f.Check || f.DryRun) {
+		return tracer.Maskf(invalidFlagError, "--watch must not be used together with --check or --dry-run")
+	}
+	if f.Timeout < 0 {
+		return tracer.Maskf(invalidFlagError, "--timeout must not be negative")
+	}
+	if f.Prune != "" && f.Prune != engine.PruneDelete && f.Prune != engine.PruneList {
+		return tracer.Maskf(invalidFlagError, "--prune must be %s or %s", engine.PruneDelete, engine.PruneList)
+	}
+	if f.Prune != "" && (f.Check || f.DryRun) {
+		return tracer.Maskf(invalidFlagError, "--prune must not be used together with --check or --dry-run")
+	}
+
+	return nil
+}
@@ -1,9 +1,13 @@
 package golang
 
 import (
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/xh3b4sd/logger"
 	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/engine"
+	"github.com/xh3b4sd/pag/pkg/schema"
 )
 
 const (
@@ -13,21 +17,36 @@ const (
 )
 
 type Config struct {
-	Logger logger.Interface
+	Engine     *engine.Engine
+	FileSystem afero.Fs
+	Logger     logger.Interface
+	Schemas    *schema.Cache
 }
 
 func New(config Config) (*cobra.Command, error) {
+	if config.Engine == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Engine must not be empty", config)
+	}
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
 	if config.Logger == nil {
 		return nil, tracer.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
+	if config.Schemas == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Schemas must not be empty", config)
+	}
 
 	var c *cobra.Command
 	{
 		f := &flag{}
 
 		r := &runner{
-			flag:   f,
-			logger: config.Logger,
+			engine:     config.Engine,
+			fileSystem: config.FileSystem,
+			flag:       f,
+			logger:     config.Logger,
+			schemas:    config.Schemas,
 		}
 
 		c = &cobra.Command{
@@ -6,14 +6,6 @@ import (
 	"github.com/xh3b4sd/tracer"
 )
 
-var commandExecutionFailedError = &tracer.Error{
-	Kind: "commandExecutionFailedError",
-}
-
-func IsCommandExecutionFailed(err error) bool {
-	return errors.Is(err, commandExecutionFailedError)
-}
-
 var invalidConfigError = &tracer.Error{
 	Kind: "invalidConfigError",
 }
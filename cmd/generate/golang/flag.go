@@ -3,15 +3,25 @@ package golang
 import (
 	"github.com/spf13/cobra"
 	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/generate/golang"
 )
 
 type flag struct {
 	Destination string
+	Exclude     []string
+	Gateway     bool
+	Includes    []string
+	Services    []string
 	Source      string
 }
 
 func (f *flag) Init(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&f.Destination, "destination", "d", "./pkg/", "Directory to put the generated golang code into.")
+	cmd.Flags().StringSliceVarP(&f.Exclude, "exclude", "e", nil, "Files and directories to ignore when looking for the gRPC api schema definitions.")
+	cmd.Flags().BoolVarP(&f.Gateway, "gateway", "g", false, "Whether to generate grpc-gateway reverse proxies and OpenAPI v2 specifications.")
+	cmd.Flags().StringSliceVarP(&f.Includes, "include", "i", nil, "Directories to look for third party schema definitions. The google/api/annotations.proto schema is provided for --gateway already.")
+	cmd.Flags().StringSliceVar(&f.Services, "services", []string{golang.ServiceGRPC}, "Service implementations to generate, either grpc, connect or both. Defaults to grpc if empty.")
 	cmd.Flags().StringVarP(&f.Source, "source", "s", ".", "Directory to look for the gRPC api schema definitions.")
 }
 
@@ -19,6 +29,11 @@ func (f *flag) Validate() error {
 	if f.Destination == "" {
 		return tracer.Maskf(invalidFlagError, "-d/--destination must not be empty")
 	}
+	for _, s := range f.Services {
+		if s != golang.ServiceConnect && s != golang.ServiceGRPC {
+			return tracer.Maskf(invalidFlagError, "--services must only contain %s or %s", golang.ServiceConnect, golang.ServiceGRPC)
+		}
+	}
 	if f.Source == "" {
 		return tracer.Maskf(invalidFlagError, "-s/--source must not be empty")
 	}
@@ -0,0 +1,33 @@
+package java
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/xh3b4sd/tracer"
+)
+
+type flag struct {
+	Destination string
+	Exclude     []string
+	Kotlin      bool
+	Package     string
+	Source      string
+}
+
+func (f *flag) Init(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.Destination, "destination", "d", "./src/main/java/", "Directory to put the generated java code into.")
+	cmd.Flags().StringSliceVarP(&f.Exclude, "exclude", "e", nil, "Files and directories to ignore when looking for the gRPC api schema definitions.")
+	cmd.Flags().BoolVarP(&f.Kotlin, "kotlin", "k", false, "Whether to additionally generate kotlin code.")
+	cmd.Flags().StringVarP(&f.Package, "package", "p", "", "Package prefix of the java packages computed per schema directory, e.g. com.example.")
+	cmd.Flags().StringVarP(&f.Source, "source", "s", ".", "Directory to look for the gRPC api schema definitions.")
+}
+
+func (f *flag) Validate() error {
+	if f.Destination == "" {
+		return tracer.Maskf(invalidFlagError, "-d/--destination must not be empty")
+	}
+	if f.Source == "" {
+		return tracer.Maskf(invalidFlagError, "-s/--source must not be empty")
+	}
+
+	return nil
+}
@@ -1,19 +1,80 @@
 package generate
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/xh3b4sd/logger"
 	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/archive"
+	"github.com/xh3b4sd/pag/pkg/config"
+	"github.com/xh3b4sd/pag/pkg/engine"
+	"github.com/xh3b4sd/pag/pkg/schema"
+	"github.com/xh3b4sd/pag/pkg/watch"
+)
+
+const (
+	// debounce is the duration protocol buffer files must not change anymore
+	// before generating code again in watch mode.
+	debounce = 200 * time.Millisecond
+	// interval is the duration between two checks for changed protocol
+	// buffer files in watch mode.
+	interval = 500 * time.Millisecond
 )
 
 type runner struct {
-	logger logger.Interface
+	all        *cobra.Command
+	engine     *engine.Engine
+	fileSystem afero.Fs
+	flag       *flag
+	logger     logger.Interface
+	output     io.Writer
+	plugins    []string
+	schemas    *schema.Cache
+}
+
+// PreRun is executed before any generate sub command. It loads the project
+// configuration file and applies the values declared for the executed sub
+// command to all of its flags not explicitly set on the command line.
+func (r *runner) PreRun(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	err := r.flag.Validate()
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	err = r.preRun(ctx, cmd, args)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+// PostRun is executed after any generate sub command. It executes all
+// generators the sub command added to the engine in one pass.
+func (r *runner) PostRun(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	err := r.postRun(ctx, cmd, args)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
 }
 
 func (r *runner) Run(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	ctx := cmd.Context()
 
 	err := r.run(ctx, cmd, args)
 	if err != nil {
@@ -23,6 +84,249 @@ func (r *runner) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func (r *runner) preRun(ctx context.Context, cmd *cobra.Command, args []string) error {
+	var err error
+
+	// The generate command itself only prints its help and does not use any
+	// project configuration.
+	if cmd.Name() == name {
+		return nil
+	}
+
+	var c config.Config
+	{
+		c, err = config.Discover(r.fileSystem, r.flag.Config)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	// The config flag is updated with the absolute path of the configuration
+	// file for all code looking it up again later on.
+	if c.Path != "" {
+		err := cmd.Flags().Set("config", c.Path)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	// The all command applies the project configuration of every declared
+	// target on its own.
+	if cmd == r.all {
+		err := c.Defaults(cmd)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	} else {
+		l := c.Lookup(cmd.Name())
+		if len(l) > 1 {
+			return tracer.Maskf(invalidConfigError, "%s must not declare multiple targets for language %s", c.Path, cmd.Name())
+		}
+
+		t := config.Target{Language: cmd.Name()}
+		if len(l) == 1 {
+			t = l[0]
+		}
+
+		err := c.Apply(cmd, t)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	// Plugin directories declared in the configuration file are relative to
+	// the configuration file, while the ones given on the command line are
+	// relative to the working directory.
+	{
+		r.plugins = nil
+
+		if cmd.Flags().Changed("plugins") {
+			r.plugins = r.flag.Plugins
+		} else {
+			for _, p := range c.Plugins {
+				r.plugins = append(r.plugins, c.Resolve(p))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *runner) postRun(ctx context.Context, cmd *cobra.Command, args []string) error {
+	// The generate command itself only prints its help and does not add any
+	// generator to the engine.
+	if cmd.Name() == name {
+		return nil
+	}
+
+	o := engine.Options{
+		Cache:   r.flag.Cache,
+		Check:   r.flag.Check,
+		DryRun:  r.flag.DryRun,
+		Format:  r.flag.Format,
+		Jobs:    r.flag.Jobs,
+		Plugins: r.plugins,
+		Prune:   r.flag.Prune,
+		Timeout: r.flag.Timeout,
+	}
+
+	if r.flag.Watch {
+		err := r.watch(ctx, cmd, args, o)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		return nil
+	}
+
+	err := r.execute(ctx, o)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+// execute executes all queued targets. Given an archive, the generated code
+// is installed into memory and written into the archive afterwards, without
+// touching any destination.
+func (r *runner) execute(ctx context.Context, o engine.Options) error {
+	if r.flag.Archive == "" {
+		err := r.engine.Execute(ctx, o)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		return nil
+	}
+
+	// The in-memory file system is rooted at "/", so that relative
+	// destinations are archived as they are, e.g. "pkg/pbf/user/api.pb.go",
+	// and absolute destinations relative to the root directory.
+	m := afero.NewMemMapFs()
+	o.Destination = afero.NewBasePathFs(m, "/")
+
+	err := r.engine.Execute(ctx, o)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	// The archive is written in one go once it is complete, so that failures
+	// never leave partially written archives behind.
+	var b bytes.Buffer
+	{
+		err := archive.Write(&b, m, "/", archive.Format(r.flag.Archive))
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	err = r.fileSystem.MkdirAll(filepath.Dir(r.flag.Archive), os.ModePerm)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	err = afero.WriteFile(r.fileSystem, r.flag.Archive, b.Bytes(), 0644)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+// watch executes the queued targets again whenever protocol buffer files of
+// any scanned source got created, changed or removed, until ctx is cancelled.
+// Every execution scans all sources again and queues the targets of the sub
+// command again. Generated code is always cached while watching, so that only
+// the commands of changed schemas are executed again. Without a configured
+// cache, a temporary cache is used until watching stops.
+func (r *runner) watch(ctx context.Context, cmd *cobra.Command, args []string, o engine.Options) error {
+	var err error
+
+	if o.Cache == "" {
+		o.Cache, err = afero.TempDir(r.fileSystem, "", "pag-watch-")
+		if err != nil {
+			return tracer.Mask(err)
+		}
+		defer r.fileSystem.RemoveAll(o.Cache)
+	}
+
+	var w *watch.Watcher
+	{
+		c := watch.Config{
+			Debounce:    debounce,
+			Fingerprint: r.fingerprint,
+			Interval:    interval,
+		}
+
+		w, err = watch.New(c)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	for {
+		err := w.Snapshot()
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		// Failed executions do not stop watching, since the next change may
+		// very well fix the problem, e.g. a syntax error.
+		{
+			s := time.Now()
+
+			err := r.execute(ctx, o)
+			if ctx.Err() != nil {
+				return nil
+			} else if err != nil {
+				fmt.Fprintf(r.output, "[%s] generation failed: %s\n", s.Format("15:04:05"), err)
+			} else {
+				fmt.Fprintf(r.output, "[%s] generation succeeded in %s\n", s.Format("15:04:05"), time.Since(s).Round(time.Millisecond))
+			}
+		}
+
+		fmt.Fprintf(r.output, "[%s] watching for changes\n", time.Now().Format("15:04:05"))
+
+		l, err := w.Wait(ctx)
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return tracer.Mask(err)
+		}
+
+		for _, p := range l {
+			fmt.Fprintf(r.output, "[%s] changed %s\n", time.Now().Format("15:04:05"), p)
+		}
+
+		r.schemas.Reset()
+
+		err = cmd.RunE(cmd, args)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+}
+
+// fingerprint returns the fingerprints of the protocol buffer files of all
+// sources scanned during the last execution.
+func (r *runner) fingerprint() (map[string]string, error) {
+	m := map[string]string{}
+
+	for _, s := range r.schemas.Schemas() {
+		f, err := s.Fingerprint()
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		for k, v := range f {
+			m[k] = v
+		}
+	}
+
+	return m, nil
+}
+
 func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
 	err := cmd.Help()
 	if err != nil {
@@ -7,11 +7,13 @@ import (
 
 type flag struct {
 	Destination string
+	Exclude     []string
 	Source      string
 }
 
 func (f *flag) Init(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&f.Destination, "destination", "d", "./src/", "Directory to put the generated golang code into.")
+	cmd.Flags().StringSliceVarP(&f.Exclude, "exclude", "e", nil, "Files and directories to ignore when looking for the gRPC api schema definitions.")
 	cmd.Flags().StringVarP(&f.Source, "source", "s", ".", "Directory to look for the gRPC api schema definitions.")
 }
 
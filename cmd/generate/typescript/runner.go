@@ -2,23 +2,24 @@ package typescript
 
 import (
 	"context"
-	"io/ioutil"
-	"os"
-	"os/exec"
-	"path/filepath"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/xh3b4sd/logger"
 	"github.com/xh3b4sd/tracer"
 
+	"github.com/xh3b4sd/pag/pkg/engine"
 	"github.com/xh3b4sd/pag/pkg/generate"
 	"github.com/xh3b4sd/pag/pkg/generate/typescript"
+	"github.com/xh3b4sd/pag/pkg/schema"
 )
 
 type runner struct {
-	flag   *flag
-	logger logger.Interface
+	engine     *engine.Engine
+	fileSystem afero.Fs
+	flag       *flag
+	logger     logger.Interface
+	schemas    *schema.Cache
 }
 
 func (r *runner) Run(cmd *cobra.Command, args []string) error {
@@ -40,66 +41,45 @@ func (r *runner) Run(cmd *cobra.Command, args []string) error {
 func (r *runner) run(ctx context.Context, cmd *cobra.Command, args []string) error {
 	var err error
 
-	var g generate.Interface
+	var s *schema.Schema
 	{
-		c := typescript.Config{
-			FileSystem: afero.NewOsFs(),
+		c := schema.Config{
+			FileSystem: r.fileSystem,
 
-			Destination: r.flag.Destination,
-			Source:      r.flag.Source,
+			Exclude: r.flag.Exclude,
+			Source:  r.flag.Source,
 		}
 
-		g, err = typescript.New(c)
+		s, err = r.schemas.Schema(c)
 		if err != nil {
 			return tracer.Mask(err)
 		}
 	}
 
-	{
-		l, err := g.Commands()
-		if err != nil {
-			return tracer.Mask(err)
-		}
-
-		for _, c := range l {
-			// The gRPC tooling is not particularly prudent with file path and
-			// file system management. We need to ensure the configured
-			// directory structure in advance so that the gRPC tooling can
-			// generate the language specific code into that.
-			err := os.MkdirAll(c.Directory, os.ModePerm)
-			if err != nil {
-				return tracer.Mask(err)
+	// The generator is created for every destination the engine asks for,
+	// e.g. temporary directories when checking the generated code.
+	t := engine.Target{
+		Destination: r.flag.Destination,
+		Generator: func(dst string) (generate.Interface, error) {
+			c := typescript.Config{
+				FileSystem: r.fileSystem,
+				Schema:     s,
+
+				Destination: dst,
+				Exclude:     r.flag.Exclude,
+				Source:      r.flag.Source,
 			}
 
-			out, err := exec.Command(c.Binary, c.Arguments...).CombinedOutput()
-			if err != nil {
-				return tracer.Maskf(commandExecutionFailedError, "%s", out)
-			}
-		}
-	}
-
-	{
-		l, err := g.Files()
-		if err != nil {
-			return tracer.Mask(err)
-		}
-
-		for _, f := range l {
-			// The generated files may define arbitrary file paths on the file
-			// system. In order to be super save we simply ensure that the
-			// directory in which the generated file is supposed to be written
-			// to exists.
-			err := os.MkdirAll(filepath.Dir(f.Path), os.ModePerm)
+			g, err := typescript.New(c)
 			if err != nil {
-				return tracer.Mask(err)
+				return nil, tracer.Mask(err)
 			}
 
-			err = ioutil.WriteFile(f.Path, f.Bytes, 0600)
-			if err != nil {
-				return tracer.Mask(err)
-			}
-		}
+			return g, nil
+		},
 	}
 
+	r.engine.Add(t)
+
 	return nil
 }
@@ -1,11 +1,22 @@
 module github.com/xh3b4sd/pag
 
-go 1.16
+go 1.21
 
 require (
-	github.com/google/go-cmp v0.5.6
+	github.com/bufbuild/protocompile v0.14.1
+	github.com/google/go-cmp v0.6.0
 	github.com/spf13/afero v1.6.0
 	github.com/spf13/cobra v1.2.1
 	github.com/xh3b4sd/logger v0.2.0
 	github.com/xh3b4sd/tracer v0.4.0
+	google.golang.org/protobuf v1.34.2
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/go-stack/stack v1.8.0 // indirect
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/text v0.3.5 // indirect
 )
@@ -2,18 +2,36 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/xh3b4sd/logger"
 	"github.com/xh3b4sd/tracer"
 
 	"github.com/xh3b4sd/pag/cmd"
+	"github.com/xh3b4sd/pag/pkg/executor"
+	"github.com/xh3b4sd/pag/pkg/executor/compiler"
 )
 
 func main() {
-	err := mainE(context.Background())
+	// Generation is cancelled on SIGINT and SIGTERM, so that running
+	// commands are stopped and temporary output is cleaned up before exiting.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	err := mainE(ctx)
+	stop()
+
 	if err != nil {
-		tracer.Panic(err)
+		// Failures are reported as plain error messages instead of stack
+		// traces, since they are mostly caused by invalid schemas or
+		// configurations and not by pag itself. Failed commands already
+		// printed their diagnostics at this point.
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
 	}
 }
 
@@ -30,10 +48,41 @@ func mainE(ctx context.Context) error {
 		}
 	}
 
+	fs := afero.NewOsFs()
+
+	var x *executor.Executor
+	{
+		c := executor.Config{}
+
+		x, err = executor.New(c)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	// Protoc commands are executed without protoc, by compiling the schemas
+	// and invoking the protoc plugins directly. Only the code generators
+	// built into protoc, e.g. --java_out, still require protoc to be
+	// installed.
+	var e *compiler.Compiler
+	{
+		c := compiler.Config{
+			Fallback:   x,
+			FileSystem: fs,
+		}
+
+		e, err = compiler.New(c)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
 	var r *cobra.Command
 	{
 		c := cmd.Config{
-			Logger: l,
+			Executor:   e,
+			FileSystem: fs,
+			Logger:     l,
 		}
 
 		r, err = cmd.New(c)
@@ -42,7 +91,7 @@ func mainE(ctx context.Context) error {
 		}
 	}
 
-	err = r.Execute()
+	err = r.ExecuteContext(ctx)
 	if err != nil {
 		return tracer.Mask(err)
 	}
@@ -0,0 +1,200 @@
+// Package archive writes files of a file system into tar.gz and zip archives,
+// e.g. in order to ship generated code without installing it into any
+// destination.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/engine"
+)
+
+const (
+	// FormatTarGz writes gzip compressed tar archives, e.g. "gen.tar.gz".
+	FormatTarGz = "tar.gz"
+	// FormatZip writes zip archives, e.g. "gen.zip".
+	FormatZip = "zip"
+)
+
+// epoch is the modification time of all archived files, so that archives of
+// the same files are identical byte for byte. Zip archives cannot represent
+// any time before 1980.
+var epoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Format returns the archive format implied by the extension of the given
+// path, or an empty string if the extension is not supported.
+func Format(p string) string {
+	if strings.HasSuffix(p, "."+FormatTarGz) || strings.HasSuffix(p, ".tgz") {
+		return FormatTarGz
+	}
+	if strings.HasSuffix(p, "."+FormatZip) {
+		return FormatZip
+	}
+
+	return ""
+}
+
+// Write writes all files within root of the given file system into w using
+// the given format. Entries are named relative to root and ordered lexically.
+// The internal state of pag, e.g. manifests, is not archived.
+func Write(w io.Writer, fs afero.Fs, root string, format string) error {
+	var a writer
+	switch format {
+	case FormatTarGz:
+		a = newTarGzWriter(w)
+	case FormatZip:
+		a = newZipWriter(w)
+	default:
+		return tracer.Maskf(invalidFormatError, "format must be %s or %s", FormatTarGz, FormatZip)
+	}
+
+	walkFunc := func(p string, i os.FileInfo, err error) error {
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		if i.IsDir() && i.Name() == engine.Internal {
+			return filepath.SkipDir
+		}
+
+		if i.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		b, err := afero.ReadFile(fs, p)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		err = a.file(filepath.ToSlash(rel), i.Mode().Perm(), b)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		return nil
+	}
+
+	err := afero.Walk(fs, root, walkFunc)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	err = a.close()
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+// writer adds files to archives of a specific format.
+type writer interface {
+	file(name string, mode os.FileMode, b []byte) error
+	close() error
+}
+
+type tarGzWriter struct {
+	gzip *gzip.Writer
+	tar  *tar.Writer
+}
+
+func newTarGzWriter(w io.Writer) *tarGzWriter {
+	g := gzip.NewWriter(w)
+
+	return &tarGzWriter{
+		gzip: g,
+		tar:  tar.NewWriter(g),
+	}
+}
+
+func (t *tarGzWriter) file(name string, mode os.FileMode, b []byte) error {
+	h := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(mode),
+		Size:     int64(len(b)),
+		ModTime:  epoch,
+	}
+
+	err := t.tar.WriteHeader(h)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	_, err = t.tar.Write(b)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+func (t *tarGzWriter) close() error {
+	err := t.tar.Close()
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	err = t.gzip.Close()
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+type zipWriter struct {
+	zip *zip.Writer
+}
+
+func newZipWriter(w io.Writer) *zipWriter {
+	return &zipWriter{
+		zip: zip.NewWriter(w),
+	}
+}
+
+func (z *zipWriter) file(name string, mode os.FileMode, b []byte) error {
+	h := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: epoch,
+	}
+
+	h.SetMode(mode)
+
+	f, err := z.zip.CreateHeader(h)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	_, err = f.Write(b)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+func (z *zipWriter) close() error {
+	err := z.zip.Close()
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_Archive_Write tests the archiving of files. The tests here ensure that
+// all files within the given root are archived relative to the root, except
+// the internal state of pag. The golden files contain the name, mode, time
+// and content of every archived file, in the order of the archive.
+//
+//	go test ./pkg/archive -run Test_Archive_Write -update
+func Test_Archive_Write(t *testing.T) {
+	testCases := []struct {
+		format string
+	}{
+		// Case 0 ensures that tar.gz archives are written.
+		{
+			format: FormatTarGz,
+		},
+		// Case 1 ensures that zip archives are written.
+		{
+			format: FormatZip,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+
+			mustWriteFile(fs, "/gen/pkg/pbf/user/api.pb.go", "package user\n", 0644)
+			mustWriteFile(fs, "/gen/pkg/.pag/manifest.json", "{}\n", 0600)
+			mustWriteFile(fs, "/gen/src/index.ts", "export {};\n", 0600)
+			mustWriteFile(fs, "/other/ignored.go", "package other\n", 0600)
+
+			var b bytes.Buffer
+			err := Write(&b, fs, "/gen", tc.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual []byte
+			if tc.format == FormatTarGz {
+				actual = mustListTarGz(b.Bytes())
+			} else {
+				actual = mustListZip(b.Bytes())
+			}
+
+			p := filepath.Join("testdata/write", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Archive_Format tests the detection of archive formats based on file
+// extensions.
+func Test_Archive_Format(t *testing.T) {
+	testCases := []struct {
+		path   string
+		format string
+	}{
+		// Case 0 ensures that tar.gz archives are detected.
+		{
+			path:   "gen/code.tar.gz",
+			format: FormatTarGz,
+		},
+		// Case 1 ensures that the short tar.gz extension is detected.
+		{
+			path:   "code.tgz",
+			format: FormatTarGz,
+		},
+		// Case 2 ensures that zip archives are detected.
+		{
+			path:   "code.zip",
+			format: FormatZip,
+		},
+		// Case 3 ensures that uncompressed tar archives are not supported.
+		{
+			path:   "code.tar",
+			format: "",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			f := Format(tc.path)
+			if f != tc.format {
+				t.Fatalf("expected %q got %q", tc.format, f)
+			}
+		})
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
+
+func mustListTarGz(b []byte) []byte {
+	g, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		panic(err)
+	}
+
+	var l bytes.Buffer
+
+	r := tar.NewReader(g)
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			panic(err)
+		}
+
+		c, err := ioutil.ReadAll(r)
+		if err != nil {
+			panic(err)
+		}
+
+		l.WriteString(fmt.Sprintf("==> %s %s %s\n", h.Name, h.FileInfo().Mode(), h.ModTime.UTC()))
+		l.Write(c)
+	}
+
+	return l.Bytes()
+}
+
+func mustListZip(b []byte) []byte {
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		panic(err)
+	}
+
+	var l bytes.Buffer
+
+	for _, f := range r.File {
+		o, err := f.Open()
+		if err != nil {
+			panic(err)
+		}
+
+		c, err := ioutil.ReadAll(o)
+		if err != nil {
+			panic(err)
+		}
+
+		err = o.Close()
+		if err != nil {
+			panic(err)
+		}
+
+		l.WriteString(fmt.Sprintf("==> %s %s %s\n", f.Name, f.Mode(), f.Modified.UTC()))
+		l.Write(c)
+	}
+
+	return l.Bytes()
+}
+
+func mustWriteFile(fs afero.Fs, p string, s string, m os.FileMode) {
+	err := afero.WriteFile(fs, p, []byte(s), m)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,15 @@
+package archive
+
+import (
+	"errors"
+
+	"github.com/xh3b4sd/tracer"
+)
+
+var invalidFormatError = &tracer.Error{
+	Kind: "invalidFormatError",
+}
+
+func IsInvalidFormat(err error) bool {
+	return errors.Is(err, invalidFormatError)
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/xh3b4sd/tracer"
+	"gopkg.in/yaml.v3"
+)
+
+// paths are the names of all flags taking file system paths. Their values are
+// resolved against the directory of the configuration file.
+var paths = map[string]bool{
+	"archive":     true,
+	"cache":       true,
+	"destination": true,
+	"exclude":     true,
+	"include":     true,
+	"source":      true,
+}
+
+const (
+	// File is the name of the project configuration file, which is discovered
+	// from the working directory upward.
+	File = "pag.yaml"
+)
+
+// Config is the project configuration as declared in a pag.yaml file. It
+// allows repositories to commit their generation setup instead of relying on
+// command line flags. All relative paths declared in the configuration file
+// are relative to the directory of the configuration file, regardless of the
+// working directory pag is executed from.
+//
+//	source: .
+//	exclude:
+//	  - pbf/internal
+//	plugins:
+//	  - ./bin
+//	targets:
+//	  - language: golang
+//	    destination: ./pkg/
+//	    options:
+//	      services: [grpc, connect]
+//	  - language: typescript
+//	    destination: ./src/
+type Config struct {
+	// Path is the location of the loaded configuration file. Relative paths
+	// declared in this file are resolved against the directory of this file.
+	Path string `yaml:"-"`
+	// Source is the directory to look for the gRPC api schema definitions.
+	// Targets may override the source individually.
+	Source string `yaml:"source"`
+	// Exclude is the list of files and directories to ignore when looking for
+	// the gRPC api schema definitions.
+	Exclude []string `yaml:"exclude"`
+	// Plugins is the list of directories to look for protoc plugins, e.g.
+	// protoc-gen-go. These directories take precedence over the PATH.
+	Plugins []string `yaml:"plugins"`
+	Targets []Target `yaml:"targets"`
+}
+
+type Target struct {
+	// Language is the name of the generate sub command responsible for this
+	// target, e.g. "golang".
+	Language    string `yaml:"language"`
+	Destination string `yaml:"destination"`
+	Source      string `yaml:"source"`
+	// Options are language specific settings keyed by their command line flag
+	// names, e.g. "kotlin" or "services".
+	Options map[string]interface{} `yaml:"options"`
+}
+
+// Find looks for the configuration file starting at the given directory and
+// walking upward until the file system root is reached. Find returns the
+// absolute path of the configuration file or a notFoundError if no
+// configuration file exists.
+func Find(fs afero.Fs, dir string) (string, error) {
+	cur, err := filepath.Abs(dir)
+	if err != nil {
+		return "", tracer.Mask(err)
+	}
+
+	for {
+		p := filepath.Join(cur, File)
+
+		ok, err := afero.Exists(fs, p)
+		if err != nil {
+			return "", tracer.Mask(err)
+		}
+		if ok {
+			return p, nil
+		}
+
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return "", tracer.Maskf(notFoundError, "%s", File)
+		}
+
+		cur = parent
+	}
+}
+
+// Discover loads the configuration file at the given path. If the given path
+// is empty, the configuration file is discovered from the working directory
+// upward. The zero value is returned if no configuration file exists.
+func Discover(fs afero.Fs, path string) (Config, error) {
+	if path == "" {
+		var err error
+
+		path, err = Find(fs, ".")
+		if IsNotFound(err) {
+			return Config{}, nil
+		} else if err != nil {
+			return Config{}, tracer.Mask(err)
+		}
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return Config{}, tracer.Mask(err)
+	}
+
+	c, err := Load(fs, path)
+	if err != nil {
+		return Config{}, tracer.Mask(err)
+	}
+
+	return c, nil
+}
+
+// Load reads and validates the configuration file at the given path. Unknown
+// fields cause an invalidFileError in order to surface typos early.
+func Load(fs afero.Fs, path string) (Config, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return Config{}, tracer.Mask(err)
+	}
+
+	var c Config
+	{
+		d := yaml.NewDecoder(bytes.NewReader(b))
+		d.KnownFields(true)
+
+		err = d.Decode(&c)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Config{}, tracer.Maskf(invalidFileError, "%s: %s", path, err)
+		}
+	}
+
+	for i, t := range c.Targets {
+		if t.Language == "" {
+			return Config{}, tracer.Maskf(invalidFileError, "%s: targets[%d].language must not be empty", path, i)
+		}
+	}
+
+	c.Path = path
+
+	return c, nil
+}
+
+// Apply sets the flag values declared for the given target on the given
+// command. Flags explicitly set on the command line are not changed, so that
+// flags always override the values of the configuration file. Relative paths
+// of the configuration file and of the flag defaults are resolved against the
+// directory of the configuration file, while paths given on the command line
+// are relative to the working directory.
+func (c Config) Apply(cmd *cobra.Command, t Target) error {
+	m := c.Flags(t)
+
+	for _, k := range Names(m) {
+		f := cmd.Flags().Lookup(k)
+		if f == nil {
+			return tracer.Maskf(invalidFileError, "%s must not declare unknown option %s for language %s", c.Path, k, t.Language)
+		}
+
+		if f.Changed {
+			continue
+		}
+
+		err := cmd.Flags().Set(k, m[k])
+		if err != nil {
+			return tracer.Maskf(invalidFileError, "%s must declare valid option %s for language %s: %s", c.Path, k, t.Language, err)
+		}
+	}
+
+	err := c.Defaults(cmd)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+// Defaults resolves the relative default paths of all flags of the given
+// command against the directory of the configuration file, since they refer
+// to the project, e.g. the default source ".". Flags explicitly set on the
+// command line are not changed.
+func (c Config) Defaults(cmd *cobra.Command) error {
+	if c.Path == "" {
+		return nil
+	}
+
+	for k := range paths {
+		f := cmd.Flags().Lookup(k)
+		if f == nil || f.Changed || f.Value.Type() != "string" {
+			continue
+		}
+
+		err := cmd.Flags().Set(k, c.Resolve(f.DefValue))
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// Lookup returns all targets declared for the given language.
+func (c Config) Lookup(language string) []Target {
+	var l []Target
+	for _, t := range c.Targets {
+		if t.Language == language {
+			l = append(l, t)
+		}
+	}
+
+	return l
+}
+
+// Flags returns the command line flag values declared for the given target,
+// keyed by flag name. The returned values are meant to be applied to all
+// flags not explicitly set on the command line, so that flags override file
+// values. List options are joined by commas the same way list flags are
+// parsed. Relative paths are resolved against the directory of the
+// configuration file.
+func (c Config) Flags(t Target) map[string]string {
+	m := map[string]string{}
+
+	if t.Destination != "" {
+		m["destination"] = t.Destination
+	}
+
+	if len(c.Exclude) != 0 {
+		m["exclude"] = strings.Join(c.Exclude, ",")
+	}
+
+	if t.Source != "" {
+		m["source"] = t.Source
+	} else if c.Source != "" {
+		m["source"] = c.Source
+	}
+
+	for k, v := range t.Options {
+		m[k] = value(v)
+	}
+
+	for k, v := range m {
+		if !paths[k] {
+			continue
+		}
+
+		var l []string
+		for _, p := range strings.Split(v, ",") {
+			l = append(l, c.Resolve(p))
+		}
+
+		m[k] = strings.Join(l, ",")
+	}
+
+	return m
+}
+
+// Resolve returns the given path declared in the configuration file as seen
+// from the working directory. Relative paths are relative to the directory of
+// the configuration file, e.g. "pkg/" declared in "api/pag.yaml" resolves to
+// "api/pkg/" if the working directory is the parent of "api". Absolute paths
+// and all paths if no configuration file got loaded are returned as they are.
+func (c Config) Resolve(p string) string {
+	if c.Path == "" || p == "" || filepath.IsAbs(p) {
+		return p
+	}
+
+	a := filepath.Join(filepath.Dir(c.Path), p)
+
+	// Paths within the working directory are kept relative, so that
+	// generated commands and plans do not depend on the location of the
+	// repository. All other paths are absolute.
+	wd, err := os.Getwd()
+	if err == nil {
+		r, err := filepath.Rel(wd, a)
+		if err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			a = r
+		}
+	}
+
+	// Trailing separators are preserved, since they are meaningful for some
+	// flags, e.g. destinations.
+	if strings.HasSuffix(p, "/") || strings.HasSuffix(p, string(filepath.Separator)) {
+		a += string(filepath.Separator)
+	}
+
+	return a
+}
+
+// Names returns the sorted flag names of the given flag values, so that they
+// can be applied in a deterministic order.
+func Names(m map[string]string) []string {
+	var l []string
+	for k := range m {
+		l = append(l, k)
+	}
+
+	sort.Strings(l)
+
+	return l
+}
+
+func value(v interface{}) string {
+	l, ok := v.([]interface{})
+	if !ok {
+		return fmt.Sprint(v)
+	}
+
+	var s []string
+	for _, x := range l {
+		s = append(s, fmt.Sprint(x))
+	}
+
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,311 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_Config_Find tests the discovery of the project configuration file. The
+// tests here ensure that the closest configuration file is found from the
+// given directory upward.
+func Test_Config_Find(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		dir string
+		pat string
+	}{
+		// Case 0 ensures that the configuration file in the given directory is
+		// found.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateFile(fs, "/repo/pag.yaml", "")
+
+				return fs
+			}(),
+			dir: "/repo",
+			pat: "/repo/pag.yaml",
+		},
+		// Case 1 ensures that the configuration file in a parent directory is
+		// found.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateFile(fs, "/repo/pag.yaml", "")
+				mustCreateFile(fs, "/repo/pbf/user/api.proto", "")
+
+				return fs
+			}(),
+			dir: "/repo/pbf/user",
+			pat: "/repo/pag.yaml",
+		},
+		// Case 2 ensures that the closest configuration file is found.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateFile(fs, "/repo/pag.yaml", "")
+				mustCreateFile(fs, "/repo/api/pag.yaml", "")
+				mustCreateFile(fs, "/repo/api/pbf/user/api.proto", "")
+
+				return fs
+			}(),
+			dir: "/repo/api/pbf/user",
+			pat: "/repo/api/pag.yaml",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			p, err := Find(tc.fs, tc.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if p != tc.pat {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.pat, p))
+			}
+		})
+	}
+}
+
+// Test_Config_Find_NotFound tests that a missing configuration file causes a
+// notFoundError.
+func Test_Config_Find_NotFound(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	mustCreateFile(fs, "/repo/pbf/user/api.proto", "")
+
+	_, err := Find(fs, "/repo/pbf/user")
+	if !IsNotFound(err) {
+		t.Fatalf("expected notFoundError got %#v", err)
+	}
+}
+
+// Test_Config_Load tests the loading of the project configuration file. The
+// tests here ensure that all declared settings are loaded as declared.
+//
+//	go test ./pkg/config -run Test_Config_Load -update
+func Test_Config_Load(t *testing.T) {
+	testCases := []struct {
+		pat string
+		src string
+	}{
+		// Case 0 ensures that empty configuration files can be loaded.
+		{
+			pat: "pag.yaml",
+			src: ``,
+		},
+		// Case 1 ensures that multiple targets with their options can be
+		// loaded.
+		{
+			pat: "pag.yaml",
+			src: `source: .
+exclude:
+  - pbf/internal
+plugins:
+  - ./bin
+  - /usr/local/bin
+targets:
+  - language: golang
+    destination: ./pkg/
+    options:
+      gateway: true
+      services: [grpc, connect]
+  - language: typescript
+    destination: ./src/
+    source: ./pbf/
+`,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+
+			mustCreateFile(fs, tc.pat, tc.src)
+
+			c, err := Load(fs, tc.pat)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := json.MarshalIndent(c, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, '\n')
+
+			p := filepath.Join("testdata/load", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Config_Load_Error tests that invalid configuration files cause an
+// invalidFileError.
+func Test_Config_Load_Error(t *testing.T) {
+	testCases := []struct {
+		src string
+	}{
+		// Case 0 ensures that unknown fields are detected.
+		{
+			src: "sources: .\n",
+		},
+		// Case 1 ensures that targets without language are detected.
+		{
+			src: "targets:\n  - destination: ./pkg/\n",
+		},
+		// Case 2 ensures that malformed files are detected.
+		{
+			src: "targets: [\n",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+
+			mustCreateFile(fs, "pag.yaml", tc.src)
+
+			_, err := Load(fs, "pag.yaml")
+			if !IsInvalidFile(err) {
+				t.Fatalf("expected invalidFileError got %#v", err)
+			}
+		})
+	}
+}
+
+// Test_Config_Flags tests the translation of targets into command line flag
+// values. The tests here ensure that target values take precedence over
+// global values and that list options are formatted like list flags.
+func Test_Config_Flags(t *testing.T) {
+	testCases := []struct {
+		con Config
+		tar Target
+		fla map[string]string
+	}{
+		// Case 0 ensures that empty targets do not define any flag.
+		{
+			con: Config{},
+			tar: Target{Language: "golang"},
+			fla: map[string]string{},
+		},
+		// Case 1 ensures that global values are applied to targets.
+		{
+			con: Config{
+				Exclude: []string{"pbf/internal", "pbf/legacy"},
+				Source:  "pbf",
+			},
+			tar: Target{Language: "golang"},
+			fla: map[string]string{
+				"exclude": "pbf/internal,pbf/legacy",
+				"source":  "pbf",
+			},
+		},
+		// Case 2 ensures that target values override global values and that
+		// options are formatted accordingly.
+		{
+			con: Config{
+				Source: "pbf",
+			},
+			tar: Target{
+				Language:    "golang",
+				Destination: "pkg",
+				Source:      "api",
+				Options: map[string]interface{}{
+					"gateway":  true,
+					"services": []interface{}{"grpc", "connect"},
+				},
+			},
+			fla: map[string]string{
+				"destination": "pkg",
+				"gateway":     "true",
+				"services":    "grpc,connect",
+				"source":      "api",
+			},
+		},
+		// Case 3 ensures that relative paths are resolved against the
+		// directory of the configuration file, while absolute paths and other
+		// options are kept as they are.
+		{
+			con: Config{
+				Path:    filepath.Join(mustGetwd(), "api", File),
+				Exclude: []string{"pbf/internal", "/tmp/legacy"},
+				Source:  ".",
+			},
+			tar: Target{
+				Language:    "golang",
+				Destination: "./pkg/",
+				Options: map[string]interface{}{
+					"include":  "../vendor/proto",
+					"services": []interface{}{"grpc"},
+				},
+			},
+			fla: map[string]string{
+				"destination": "api/pkg/",
+				"exclude":     "api/pbf/internal,/tmp/legacy",
+				"include":     "vendor/proto",
+				"services":    "grpc",
+				"source":      "api",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			fla := tc.con.Flags(tc.tar)
+
+			if !cmp.Equal(tc.fla, fla) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.fla, fla))
+			}
+		})
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
+
+func mustGetwd() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+
+	return wd
+}
+
+func mustCreateFile(fs afero.Fs, p string, s string) {
+	err := afero.WriteFile(fs, p, []byte(s), 0644)
+	if err != nil {
+		panic(err)
+	}
+}
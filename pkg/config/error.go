@@ -0,0 +1,23 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/xh3b4sd/tracer"
+)
+
+var invalidFileError = &tracer.Error{
+	Kind: "invalidFileError",
+}
+
+func IsInvalidFile(err error) bool {
+	return errors.Is(err, invalidFileError)
+}
+
+var notFoundError = &tracer.Error{
+	Kind: "notFoundError",
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, notFoundError)
+}
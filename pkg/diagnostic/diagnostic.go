@@ -0,0 +1,107 @@
+// Package diagnostic parses the output of failed protoc invocations into
+// structured diagnostics, so that errors can be reported per schema file
+// instead of as raw command output.
+package diagnostic
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is a single problem protoc reported, e.g. a syntax error within
+// a schema file.
+//
+//	pbf/user/api.proto:12:3: Expected ";".
+type Diagnostic struct {
+	// Command is the command which reported the diagnostic. It is only set
+	// for diagnostics without File, e.g. failures of protoc plugins, since
+	// they cannot be attributed to any schema file otherwise.
+	Command string `json:"command,omitempty"`
+	// File is the schema file the diagnostic refers to, if any.
+	File string `json:"file,omitempty"`
+	// Line is the line within File the diagnostic refers to, starting at 1.
+	// Line is 0 if protoc did not report any position.
+	Line int `json:"line,omitempty"`
+	// Column is the column within Line the diagnostic refers to, starting at
+	// 1. Column is 0 if protoc did not report any position.
+	Column int `json:"column,omitempty"`
+	// Message is the description of the problem.
+	Message string `json:"message"`
+}
+
+// expression matches protoc diagnostics with and without position, e.g.
+// "pbf/user/api.proto:12:3: Expected \";\"." and "pbf/user/api.proto: File
+// not found.".
+var expression = regexp.MustCompile(`^(\S+\.proto):(?:(\d+):(\d+):)? (.+)$`)
+
+// Parse returns the diagnostics of the given protoc output. Every non empty
+// line results in one diagnostic. Lines not referring to any schema file are
+// attributed to the given command.
+func Parse(command string, out []byte) []Diagnostic {
+	var l []Diagnostic
+
+	for _, s := range strings.Split(string(out), "\n") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		m := expression.FindStringSubmatch(s)
+		if m == nil {
+			l = append(l, Diagnostic{Command: command, Message: s})
+			continue
+		}
+
+		d := Diagnostic{
+			File:    m[1],
+			Message: m[4],
+		}
+
+		if m[2] != "" {
+			d.Line, _ = strconv.Atoi(m[2])
+			d.Column, _ = strconv.Atoi(m[3])
+		}
+
+		l = append(l, d)
+	}
+
+	return l
+}
+
+// Sort orders the given diagnostics by file and position and removes
+// duplicates. Protoc reports the same problems for every plugin it is invoked
+// with, which is why the same diagnostic is commonly reported multiple times.
+// Diagnostics without file are ordered last, in the order they were given.
+func Sort(l []Diagnostic) []Diagnostic {
+	var s []Diagnostic
+
+	seen := map[Diagnostic]bool{}
+	for _, d := range l {
+		if seen[d] {
+			continue
+		}
+
+		seen[d] = true
+		s = append(s, d)
+	}
+
+	sort.SliceStable(s, func(i, j int) bool {
+		a, b := s[i], s[j]
+
+		if (a.File == "") != (b.File == "") {
+			return a.File != ""
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+
+		return a.Column < b.Column
+	})
+
+	return s
+}
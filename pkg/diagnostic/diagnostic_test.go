@@ -0,0 +1,94 @@
+package diagnostic
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_Diagnostic_Parse tests the parsing of protoc output. The tests here
+// ensure that diagnostics with and without position are parsed, that output
+// not referring to schema files is attributed to its command and that
+// duplicated diagnostics are removed.
+//
+//	go test ./pkg/diagnostic -run Test_Diagnostic_Parse -update
+func Test_Diagnostic_Parse(t *testing.T) {
+	testCases := []struct {
+		out []string
+	}{
+		// Case 0 ensures that empty output does not result in any
+		// diagnostic.
+		{
+			out: []string{""},
+		},
+		// Case 1 ensures that diagnostics with position are parsed and
+		// ordered by file and position.
+		{
+			out: []string{
+				"pbf/user/search.proto:4:1: Expected top-level statement (e.g. \"message\").\n" +
+					"pbf/user/api.proto:12:3: \"SearchI\" is not defined.\n" +
+					"pbf/user/api.proto:9:17: Expected \";\".\n",
+			},
+		},
+		// Case 2 ensures that diagnostics without position and output not
+		// referring to schema files are parsed.
+		{
+			out: []string{
+				"pbf/user/delete.proto: File not found.\n" +
+					"--go_out: protoc-gen-go: Plugin failed with status code 1.\n",
+			},
+		},
+		// Case 3 ensures that the same diagnostics reported by multiple
+		// commands are only reported once.
+		{
+			out: []string{
+				"pbf/user/api.proto:9:17: Expected \";\".\n",
+				"pbf/user/api.proto:9:17: Expected \";\".\n",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var l []Diagnostic
+			for j, o := range tc.out {
+				l = append(l, Parse("protoc "+strconv.Itoa(j), []byte(o))...)
+			}
+
+			actual, err := json.MarshalIndent(Sort(l), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, '\n')
+
+			p := filepath.Join("testdata/parse", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
@@ -0,0 +1,285 @@
+// Package diff implements line based unified diffs as known from diff -u and
+// git diff, using the algorithm described by Eugene W. Myers in "An O(ND)
+// Difference Algorithm and Its Variations".
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Context is the number of unchanged lines shown around every change.
+	Context = 3
+)
+
+const (
+	kindDelete = '-'
+	kindEqual  = ' '
+	kindInsert = '+'
+)
+
+type edit struct {
+	kind byte
+	line string
+}
+
+// Unified returns the unified diff transforming x into y, where a and b are
+// the names of x and y printed in the diff header, e.g. "a/pkg/user.pb.go"
+// and "b/pkg/user.pb.go". Unified returns an empty string if x and y are
+// equal.
+func Unified(a string, b string, x []byte, y []byte) string {
+	if string(x) == string(y) {
+		return ""
+	}
+
+	l := edits(lines(string(x)), lines(string(y)))
+
+	var s strings.Builder
+
+	s.WriteString(fmt.Sprintf("--- %s\n", a))
+	s.WriteString(fmt.Sprintf("+++ %s\n", b))
+
+	for _, h := range hunks(l) {
+		var xs, xl, ys, yl int
+		for _, e := range l[:h[0]] {
+			if e.kind != kindInsert {
+				xs++
+			}
+			if e.kind != kindDelete {
+				ys++
+			}
+		}
+		for _, e := range l[h[0]:h[1]] {
+			if e.kind != kindInsert {
+				xl++
+			}
+			if e.kind != kindDelete {
+				yl++
+			}
+		}
+
+		// Hunk ranges are 1-based. Empty ranges point to the line before
+		// the change, which is 0 for changes at the beginning of a file.
+		if xl != 0 {
+			xs++
+		}
+		if yl != 0 {
+			ys++
+		}
+
+		s.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(xs, xl), hunkRange(ys, yl)))
+
+		for _, e := range l[h[0]:h[1]] {
+			s.WriteString(fmt.Sprintf("%c%s\n", e.kind, e.line))
+		}
+	}
+
+	return s.String()
+}
+
+// edits returns the shortest edit script transforming x into y. The script is
+// computed with the linear space variant of the algorithm, which recursively
+// splits x and y at the middle snake of an optimal path. The memory required
+// therefore grows with the size of x and y instead of the number of edits
+// times the size of x and y.
+func edits(x []string, y []string) []edit {
+	return script(nil, x, y)
+}
+
+// script appends the shortest edit script transforming x into y to l.
+func script(l []edit, x []string, y []string) []edit {
+	// Common prefixes and suffixes are equal lines of every optimal path.
+	// Trimming them first keeps the search space small, since most changes
+	// of generated code are local.
+	var p int
+	for p < len(x) && p < len(y) && x[p] == y[p] {
+		p++
+	}
+
+	var s int
+	for s < len(x)-p && s < len(y)-p && x[len(x)-1-s] == y[len(y)-1-s] {
+		s++
+	}
+
+	for _, e := range x[:p] {
+		l = append(l, edit{kind: kindEqual, line: e})
+	}
+
+	a, b := x[p:len(x)-s], y[p:len(y)-s]
+
+	i, j, ok := middle(a, b)
+	if ok {
+		l = script(l, a[:i], b[:j])
+		l = script(l, a[i:], b[j:])
+	} else {
+		for _, e := range a {
+			l = append(l, edit{kind: kindDelete, line: e})
+		}
+		for _, e := range b {
+			l = append(l, edit{kind: kindInsert, line: e})
+		}
+	}
+
+	for _, e := range x[len(x)-s:] {
+		l = append(l, edit{kind: kindEqual, line: e})
+	}
+
+	return l
+}
+
+// middle searches for an optimal path from both ends of x and y at the same
+// time and returns the point at which both searches overlap. Splitting x and
+// y at this point results in two smaller problems, which both require about
+// half of the edits. middle returns false if x and y do not have any line in
+// common, in which case all lines of x are deleted and all lines of y are
+// inserted.
+func middle(x []string, y []string) (int, int, bool) {
+	n, m := len(x), len(y)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+
+	max := (n + m + 1) / 2
+	off := max
+	size := 2*max + 2
+
+	// fwd holds the furthest reaching x of every diagonal of the forward
+	// search, bwd the same of the backward search, counted from the end of x.
+	fwd := make([]int, size)
+	bwd := make([]int, size)
+	for i := range fwd {
+		fwd[i] = -1
+		bwd[i] = -1
+	}
+	fwd[off+1] = 0
+	bwd[off+1] = 0
+
+	delta := n - m
+
+	// Paths of both searches can only overlap on diagonals reachable by
+	// both, which depends on whether the difference of the lengths of x and
+	// y is odd or even.
+	odd := delta%2 != 0
+
+	// Diagonals leaving x or y are not searched any further.
+	var fs, fe, bs, be int
+	for d := 0; d < max; d++ {
+		for k := -d + fs; k <= d-fe; k += 2 {
+			o := off + k
+
+			var i int
+			if k == -d || (k != d && fwd[o-1] < fwd[o+1]) {
+				i = fwd[o+1]
+			} else {
+				i = fwd[o-1] + 1
+			}
+
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+
+			fwd[o] = i
+
+			if i > n {
+				fe += 2
+			} else if j > m {
+				fs += 2
+			} else if odd {
+				r := off + delta - k
+				if r >= 0 && r < size && bwd[r] != -1 && i >= n-bwd[r] {
+					return i, j, true
+				}
+			}
+		}
+
+		for k := -d + bs; k <= d-be; k += 2 {
+			o := off + k
+
+			var i int
+			if k == -d || (k != d && bwd[o-1] < bwd[o+1]) {
+				i = bwd[o+1]
+			} else {
+				i = bwd[o-1] + 1
+			}
+
+			j := i - k
+			for i < n && j < m && x[n-i-1] == y[m-j-1] {
+				i++
+				j++
+			}
+
+			bwd[o] = i
+
+			if i > n {
+				be += 2
+			} else if j > m {
+				bs += 2
+			} else if !odd {
+				r := off + delta - k
+				if r >= 0 && r < size && fwd[r] != -1 && fwd[r] >= n-i {
+					return fwd[r], fwd[r] - (r - off), true
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// hunks returns the start and end indices of all hunks within the given edit
+// script. Changes closer than two times the context are merged into the same
+// hunk.
+func hunks(l []edit) [][2]int {
+	var h [][2]int
+	for i, e := range l {
+		if e.kind == kindEqual {
+			continue
+		}
+
+		s := i - Context
+		if s < 0 {
+			s = 0
+		}
+
+		f := i + Context + 1
+		if f > len(l) {
+			f = len(l)
+		}
+
+		if len(h) != 0 && s <= h[len(h)-1][1] {
+			h[len(h)-1][1] = f
+		} else {
+			h = append(h, [2]int{s, f})
+		}
+	}
+
+	return h
+}
+
+func hunkRange(s int, l int) string {
+	if l == 1 {
+		return fmt.Sprintf("%d", s)
+	}
+
+	return fmt.Sprintf("%d,%d", s, l)
+}
+
+// lines splits the given string into lines. A missing newline at the end of
+// the string is marked the same way diff -u does.
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	l := strings.Split(s, "\n")
+	if l[len(l)-1] == "" {
+		return l[:len(l)-1]
+	}
+
+	l[len(l)-1] += "\n\\ No newline at end of file"
+
+	return l
+}
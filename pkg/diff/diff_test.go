@@ -0,0 +1,93 @@
+package diff
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_Diff_Unified tests the computation of unified diffs. The tests here
+// ensure that insertions, deletions and changes are rendered with their
+// context the same way diff -u renders them.
+//
+//	go test ./pkg/diff -run Test_Diff_Unified -update
+func Test_Diff_Unified(t *testing.T) {
+	testCases := []struct {
+		x string
+		y string
+	}{
+		// Case 0 ensures that equal contents do not result in any diff.
+		{
+			x: "foo\nbar\n",
+			y: "foo\nbar\n",
+		},
+		// Case 1 ensures that new files are rendered entirely as insertions.
+		{
+			x: "",
+			y: "foo\nbar\n",
+		},
+		// Case 2 ensures that deleted files are rendered entirely as
+		// deletions.
+		{
+			x: "foo\nbar\n",
+			y: "",
+		},
+		// Case 3 ensures that changes are rendered with their context.
+		{
+			x: "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n",
+			y: "a\nb\nc\nd\nE\nf\ng\nh\ni\nj\n",
+		},
+		// Case 4 ensures that changes far apart from each other are rendered
+		// as separate hunks.
+		{
+			x: "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n",
+			y: "A\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\no\n",
+		},
+		// Case 5 ensures that missing newlines at the end of files are
+		// rendered.
+		{
+			x: "foo\nbar",
+			y: "foo\nbar\n",
+		},
+		// Case 6 ensures that interleaved changes across the whole file are
+		// rendered as minimal edits.
+		{
+			x: "a\nb\nc\na\nb\nb\na\n",
+			y: "c\nb\na\nb\na\nc\n",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			actual := []byte(Unified("a/foo.go", "b/foo.go", []byte(tc.x), []byte(tc.y)))
+
+			p := filepath.Join("testdata/unified", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
@@ -0,0 +1,402 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/executor"
+	"github.com/xh3b4sd/pag/pkg/generate"
+	"github.com/xh3b4sd/pag/pkg/schema"
+)
+
+// expiry is the duration after which cache entries which did not get used
+// anymore are removed from the cache.
+const expiry = 30 * 24 * time.Hour
+
+// version is part of every cache key, so that changes of the cache layout or
+// key derivation invalidate all existing cache entries.
+const version = "pag/cache/v2"
+
+// entry is the cached result of a single command, which are the files the
+// command generated relative to its destination.
+type entry struct {
+	Files []entryFile `json:"files"`
+}
+
+type entryFile struct {
+	Bytes []byte      `json:"bytes"`
+	Mode  os.FileMode `json:"mode"`
+	Path  string      `json:"path"`
+}
+
+// key returns the cache key of the given job. The key covers everything the
+// generated files of the command depend on.
+//
+//	the command itself including all generator options
+//	the content of all schema files and their transitive imports
+//	the identity of protoc and all of its plugins
+//
+// The temporary destination of the job is not part of the key, since it is
+// different for every execution. The identities of all binaries are memoized
+// in ids, so that every binary is only hashed once per execution.
+func (e *Engine) key(j job, ids map[string]string) (string, error) {
+	h := sha256.New()
+
+	line(h, version)
+	line(h, j.command.Binary)
+	for _, a := range j.command.Arguments {
+		line(h, strings.ReplaceAll(a, j.directory, "$DST"))
+	}
+	line(h, strings.ReplaceAll(j.command.Directory, j.directory, "$DST"))
+
+	for _, t := range tools(j.command) {
+		id, err := identity(t, j.command.Plugins, ids)
+		if err != nil {
+			return "", tracer.Mask(err)
+		}
+
+		line(h, t, id)
+	}
+
+	l, err := inputs(e.fileSystem, j.command)
+	if err != nil {
+		return "", tracer.Mask(err)
+	}
+
+	for _, p := range l {
+		b, err := afero.ReadFile(e.fileSystem, p)
+		if err != nil {
+			return "", tracer.Mask(err)
+		}
+
+		line(h, strings.ReplaceAll(p, j.directory, "$DST"), fmt.Sprintf("%x", sha256.Sum256(b)))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// inputs returns the paths of all schema files the given command reads,
+// including all transitively imported schema files which can be found within
+// the proto paths of the command. Imports which cannot be found, e.g. the well
+// known types shipped with protoc, are covered by the identity of protoc.
+func inputs(fs afero.Fs, c generate.Command) ([]string, error) {
+	var inc []string
+	var src []string
+	for _, a := range c.Arguments {
+		switch {
+		case strings.HasPrefix(a, "--proto_path="):
+			inc = append(inc, strings.TrimPrefix(a, "--proto_path="))
+		case strings.HasPrefix(a, "-I"):
+			inc = append(inc, strings.TrimPrefix(a, "-I"))
+		case strings.HasSuffix(a, ".proto") && !strings.HasPrefix(a, "-"):
+			src = append(src, a)
+		}
+	}
+
+	see := map[string]bool{}
+
+	var l []string
+	var walk func(p string) error
+	walk = func(p string) error {
+		p = filepath.Clean(p)
+		if see[p] {
+			return nil
+		}
+		see[p] = true
+
+		b, err := afero.ReadFile(fs, p)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return tracer.Mask(err)
+		}
+
+		l = append(l, p)
+
+		// Schema files which cannot be parsed are still part of the key,
+		// just without their imports. Protoc is going to report them anyway.
+		f, err := schema.Parse(p, b)
+		if err != nil {
+			return nil
+		}
+
+		for _, i := range f.Imports {
+			for _, d := range inc {
+				ok, err := afero.Exists(fs, filepath.Join(d, i.Path))
+				if err != nil {
+					return tracer.Mask(err)
+				}
+
+				if ok {
+					err := walk(filepath.Join(d, i.Path))
+					if err != nil {
+						return tracer.Mask(err)
+					}
+
+					break
+				}
+			}
+		}
+
+		return nil
+	}
+
+	for _, s := range src {
+		p := s
+
+		ok, err := afero.Exists(fs, p)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		for _, d := range inc {
+			if ok {
+				break
+			}
+
+			p = filepath.Join(d, s)
+
+			ok, err = afero.Exists(fs, p)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+		}
+
+		err = walk(p)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	sort.Strings(l)
+
+	return l, nil
+}
+
+// tools returns the binaries the given command depends on, which are the
+// command's binary itself and all protoc plugins it invokes, e.g.
+// "protoc-gen-go" for --go_out.
+func tools(c generate.Command) []string {
+	l := []string{c.Binary}
+
+	plg := map[string]string{}
+	for _, a := range c.Arguments {
+		if !strings.HasPrefix(a, "--plugin=") {
+			continue
+		}
+
+		s := strings.SplitN(strings.TrimPrefix(a, "--plugin="), "=", 2)
+		if len(s) == 2 {
+			plg[s[0]] = s[1]
+		}
+	}
+
+	for _, a := range c.Arguments {
+		i := strings.Index(a, "_out=")
+		if !strings.HasPrefix(a, "--") || i == -1 {
+			continue
+		}
+
+		n := "protoc-gen-" + a[2:i]
+		if p, ok := plg[n]; ok {
+			n = p
+		}
+
+		l = append(l, n)
+	}
+
+	return l
+}
+
+// identity returns the identity of the given binary as found in the given
+// plugin directories or the PATH, which is its location and the hash of its
+// content. Binaries which cannot be found, e.g. the code generators built into
+// protoc, are covered by the identity of protoc.
+func identity(b string, plugins []string, ids map[string]string) (string, error) {
+	p, err := executor.LookPath(b, plugins)
+	if err != nil {
+		return "-", nil
+	}
+
+	if id, ok := ids[p]; ok {
+		return id, nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return "", tracer.Mask(err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", tracer.Mask(err)
+	}
+
+	ids[p] = p + " " + hex.EncodeToString(h.Sum(nil))
+
+	return ids[p], nil
+}
+
+func line(h hash.Hash, l ...string) {
+	fmt.Fprintln(h, strings.Join(l, " "))
+}
+
+// collect returns the files the given job generated into its temporary
+// destination, ignoring the inputs generators provided for the job.
+func (e *Engine) collect(j job) (*entry, error) {
+	inp := map[string]bool{}
+	for _, f := range j.inputs {
+		inp[filepath.Clean(f.Path)] = true
+	}
+
+	gen, err := e.generated([]string{j.directory})
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	h := &entry{Files: []entryFile{}}
+	for _, f := range gen.paths() {
+		if inp[filepath.Clean(gen[f])] {
+			continue
+		}
+
+		i, err := e.fileSystem.Stat(gen[f])
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		b, err := afero.ReadFile(e.fileSystem, gen[f])
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		h.Files = append(h.Files, entryFile{Bytes: b, Mode: i.Mode().Perm(), Path: f})
+	}
+
+	return h, nil
+}
+
+// restore writes the files of the given cache entry into the given temporary
+// destination.
+func (e *Engine) restore(h entry, dst string) error {
+	for _, f := range h.Files {
+		if !local(f.Path) {
+			return tracer.Maskf(invalidCacheError, "%s must be relative to its destination", f.Path)
+		}
+
+		p := filepath.Join(dst, f.Path)
+
+		err := e.fileSystem.MkdirAll(filepath.Dir(p), os.ModePerm)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		err = afero.WriteFile(e.fileSystem, p, f.Bytes, f.Mode)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the cache entry of the given key within the given cache
+// directory, or nil if there is none.
+func (e *Engine) lookup(dir string, key string) (*entry, error) {
+	b, err := afero.ReadFile(e.fileSystem, filepath.Join(dir, key[:2], key+".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	// Broken cache entries, e.g. of interrupted writes, are treated as if
+	// they did not exist, so that they simply get replaced.
+	var h entry
+	err = json.Unmarshal(b, &h)
+	if err != nil {
+		return nil, nil
+	}
+
+	// The modification time of cache entries tells when they got used the
+	// last time, which is why it is updated on every hit.
+	{
+		n := time.Now()
+
+		err := e.fileSystem.Chtimes(filepath.Join(dir, key[:2], key+".json"), n, n)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	return &h, nil
+}
+
+// expire removes all cache entries within the given cache directory, which did
+// not get used within the expiry duration, so that the cache does not grow
+// indefinitely.
+func (e *Engine) expire(dir string) error {
+	t := time.Now().Add(-expiry)
+
+	err := afero.Walk(e.fileSystem, dir, func(p string, i os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return tracer.Mask(err)
+		}
+
+		if i.IsDir() || filepath.Ext(p) != ".json" || !i.ModTime().Before(t) {
+			return nil
+		}
+
+		err = e.fileSystem.Remove(p)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+// store writes the given cache entry of the given key into the given cache
+// directory.
+func (e *Engine) store(dir string, key string, h entry) error {
+	b, err := json.Marshal(h)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	p := filepath.Join(dir, key[:2], key+".json")
+
+	err = e.fileSystem.MkdirAll(filepath.Dir(p), os.ModePerm)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	err = afero.WriteFile(e.fileSystem, p, b, 0600)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/diff"
+)
+
+// check generates the code of all queued targets into a temporary directory
+// and compares the generated files with the files in the actual
+// destinations. Only generated files are compared, because destinations may
+// contain arbitrary other files as well.
+func (e *Engine) check(ctx context.Context, o Options) error {
+	tmp, err := afero.TempDir(e.fileSystem, "", "pag-check-")
+	if err != nil {
+		return tracer.Mask(err)
+	}
+	defer e.fileSystem.RemoveAll(tmp)
+
+	dsts, err := e.build(ctx, tmp, o)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	var n int
+	for _, g := range groups(e.targets) {
+		var src []string
+		for _, i := range g {
+			src = append(src, dsts[i])
+		}
+
+		d, err := e.compare(o.Destination, src, e.targets[g[0]].Destination)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		for _, x := range d {
+			_, err := fmt.Fprint(e.output, x)
+			if err != nil {
+				return tracer.Mask(err)
+			}
+		}
+
+		n += len(d)
+	}
+
+	if n != 0 {
+		return tracer.Maskf(outOfDateError, "%d generated files differ from their destinations", n)
+	}
+
+	return nil
+}
+
+// compare returns the unified diffs of all files generated into the given
+// temporary destinations that differ from their counterparts in dst. Files
+// missing in dst are rendered as new files. Stale files recorded in the
+// manifest of dst are rendered as deleted files. The .pag directory is
+// ignored, since it only contains the internal state of pag. Destinations are
+// read from the given file system.
+func (e *Engine) compare(fs afero.Fs, src []string, dst string) ([]string, error) {
+	gen, err := e.generated(src)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	m, err := readManifest(fs, dst)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var l []string
+	for _, f := range gen.paths() {
+		if internal(f) {
+			continue
+		}
+
+		x, err := readFile(fs, filepath.Join(dst, f))
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		y, err := afero.ReadFile(e.fileSystem, gen[f])
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		d := diff.Unified("a/"+filepath.Join(dst, f), "b/"+filepath.Join(dst, f), x, y)
+		if d != "" {
+			l = append(l, d)
+		}
+	}
+
+	for _, f := range stale(fs, m.Files, gen, dst) {
+		if internal(f) {
+			continue
+		}
+
+		x, err := readFile(fs, filepath.Join(dst, f))
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		d := diff.Unified("a/"+filepath.Join(dst, f), "/dev/null", x, nil)
+		if d != "" {
+			l = append(l, d)
+		}
+	}
+
+	return l, nil
+}
+
+// internal returns whether the given path relative to a destination is part
+// of the internal state of pag.
+func internal(p string) bool {
+	return p == Internal || strings.HasPrefix(p, Internal+string(filepath.Separator))
+}
+
+// readFile returns the content of the given file or nil if the file does not
+// exist.
+func readFile(fs afero.Fs, p string) ([]byte, error) {
+	b, err := afero.ReadFile(fs, p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/diagnostic"
+)
+
+type diagnosticsJSON struct {
+	Diagnostics []diagnostic.Diagnostic `json:"diagnostics"`
+}
+
+// writeDiagnostics renders the given diagnostics in the given format. The
+// text format groups diagnostics by their schema files. Diagnostics without
+// schema file are grouped by the commands reporting them.
+//
+//	pbf/user/api.proto
+//	  9:17: Expected ";".
+//	  12:3: "SearchI" is not defined.
+//
+//	protoc --experimental_allow_proto3_optional --go_out=pkg/pbf/user/ ...
+//	  --go_out: protoc-gen-go: Plugin failed with status code 1.
+func writeDiagnostics(w io.Writer, l []diagnostic.Diagnostic, format string) error {
+	switch format {
+	case FormatJSON:
+		j := diagnosticsJSON{
+			Diagnostics: []diagnostic.Diagnostic{},
+		}
+
+		j.Diagnostics = append(j.Diagnostics, l...)
+
+		b, err := json.MarshalIndent(j, "", "  ")
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	case FormatText:
+		var g string
+		for i, d := range l {
+			h := d.File
+			if h == "" {
+				h = d.Command
+			}
+
+			if i == 0 || h != g {
+				if i != 0 {
+					_, err := fmt.Fprintln(w)
+					if err != nil {
+						return tracer.Mask(err)
+					}
+				}
+
+				_, err := fmt.Fprintln(w, h)
+				if err != nil {
+					return tracer.Mask(err)
+				}
+
+				g = h
+			}
+
+			var p string
+			if d.Line != 0 {
+				p = strconv.Itoa(d.Line) + ":" + strconv.Itoa(d.Column) + ": "
+			}
+
+			_, err := fmt.Fprintf(w, "  %s%s\n", p, d.Message)
+			if err != nil {
+				return tracer.Mask(err)
+			}
+		}
+	default:
+		return tracer.Maskf(invalidOptionsError, "format must be %s or %s", FormatJSON, FormatText)
+	}
+
+	return nil
+}
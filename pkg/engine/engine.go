@@ -0,0 +1,711 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/logger"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/diagnostic"
+	"github.com/xh3b4sd/pag/pkg/executor"
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+const (
+	// PruneDelete removes stale files from their destinations.
+	PruneDelete = "delete"
+	// PruneList prints stale files without removing them.
+	PruneList = "list"
+)
+
+const (
+	// FormatJSON renders plans as JSON.
+	FormatJSON = "json"
+	// FormatText renders plans as plain text.
+	FormatText = "text"
+)
+
+type Config struct {
+	// Executor executes the commands of all targets, e.g. as processes of
+	// the operating system.
+	Executor executor.Interface
+	// FileSystem is where the files of all targets are generated into
+	// temporary directories. Note that commands executed by the executor have
+	// to write to the same file system, e.g. afero.NewOsFs() for protoc
+	// processes. Generated files are installed into the same file system,
+	// unless Options.Destination defines another one.
+	FileSystem afero.Fs
+	Logger     logger.Interface
+	// Output is where plans are rendered to, e.g. os.Stdout.
+	Output io.Writer
+}
+
+// Engine executes the commands and writes the files of all targets added to
+// it. Generate sub commands only add their targets, so that all targets of a
+// single invocation are executed in one pass. Library users can drive the
+// generation programmatically the same way, by adding targets for any
+// generate.Interface and executing them.
+type Engine struct {
+	executor   executor.Interface
+	fileSystem afero.Fs
+	logger     logger.Interface
+	mutex      sync.Mutex
+	output     io.Writer
+
+	targets []Target
+}
+
+// Target is a single destination code is generated into, e.g. "./pkg/" for
+// golang code.
+type Target struct {
+	// Destination is the directory the target's code is generated into.
+	Destination string
+	// Generator returns the target's generator for the given destination.
+	// The engine may ask for generators of other destinations than the
+	// target's actual destination, e.g. in order to generate code into a
+	// temporary directory.
+	Generator func(destination string) (generate.Interface, error)
+}
+
+// Options are the settings of a single execution.
+type Options struct {
+	// Cache is the directory the generated files of every command are
+	// cached in. Commands are not executed again as long as their inputs did
+	// not change. Cache entries not used for 30 days are removed. Nothing is
+	// cached if Cache is empty.
+	Cache string
+	// Check defines whether to generate code into a temporary directory and
+	// compare the result with the actual destinations. All differences are
+	// rendered as unified diffs and cause an outOfDateError.
+	Check bool
+	// Destination is the file system generated files are installed into,
+	// e.g. an in-memory file system in order to archive the generated files
+	// instead of touching the actual destinations. Generated files are
+	// installed into the engine's file system if Destination is nil.
+	Destination afero.Fs
+	// DryRun defines whether to only render the plan of what would be
+	// executed without creating directories, executing commands or writing
+	// files.
+	DryRun bool
+	// Format is the format plans and diagnostics are rendered in, either
+	// "json" or "text".
+	Format string
+	// Jobs is the maximum number of commands executed concurrently. Jobs
+	// must be at least 1 unless DryRun is set.
+	Jobs int
+	// Plugins are the directories to look for protoc plugins in before
+	// looking into the PATH. They are passed to the executor with every
+	// command.
+	Plugins []string
+	// Prune defines how to handle stale files, which were generated before
+	// but are not generated anymore. Stale files are kept by default,
+	// "list" only prints them and "delete" removes them.
+	Prune string
+	// Timeout is the maximum duration of every single command. Commands
+	// running longer are cancelled and reported as failed. Commands do not
+	// time out if Timeout is 0.
+	Timeout time.Duration
+}
+
+func New(config Config) (*Engine, error) {
+	if config.Executor == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Executor must not be empty", config)
+	}
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Output == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Output must not be empty", config)
+	}
+
+	e := &Engine{
+		executor:   config.Executor,
+		fileSystem: config.FileSystem,
+		logger:     config.Logger,
+		output:     config.Output,
+	}
+
+	return e, nil
+}
+
+// Add queues the given target for the next execution.
+func (e *Engine) Add(t Target) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.targets = append(e.targets, t)
+}
+
+// Execute executes all queued targets and empties the queue afterwards.
+func (e *Engine) Execute(ctx context.Context, o Options) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	defer func() { e.targets = nil }()
+
+	if o.Format != FormatJSON && o.Format != FormatText {
+		return tracer.Maskf(invalidOptionsError, "%T.Format must be %s or %s", o, FormatJSON, FormatText)
+	}
+	if !o.DryRun && o.Jobs < 1 {
+		return tracer.Maskf(invalidOptionsError, "%T.Jobs must be greater than 0", o)
+	}
+
+	if o.Destination == nil {
+		o.Destination = e.fileSystem
+	}
+
+	if o.Check {
+		err := e.check(ctx, o)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		return nil
+	}
+
+	if o.DryRun {
+		var dsts []string
+		for _, t := range e.targets {
+			dsts = append(dsts, t.Destination)
+		}
+
+		p, err := e.plan(dsts)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		err = p.Write(e.output, o.Format)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		return nil
+	}
+
+	err := e.generate(ctx, o)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+// generate generates the code of all queued targets into a temporary
+// directory first and installs the generated files into the actual
+// destinations afterwards. That way the engine knows exactly which files got
+// generated, including the files generated by protoc, which are recorded in
+// the manifest of every destination. Files generators only stage for their
+// own commands never end up in the actual destinations. Destinations are only
+// touched once all commands succeeded and are restored if installing the
+// generated files fails.
+func (e *Engine) generate(ctx context.Context, o Options) error {
+	tmp, err := afero.TempDir(e.fileSystem, "", "pag-generate-")
+	if err != nil {
+		return tracer.Mask(err)
+	}
+	defer e.fileSystem.RemoveAll(tmp)
+
+	dsts, err := e.build(ctx, tmp, o)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	// Cancellation is only respected before installing the generated files,
+	// because stopping in the middle of the installation would leave the
+	// destinations partially updated.
+	if ctx.Err() != nil {
+		return tracer.Maskf(executionCancelledError, "%s", ctx.Err())
+	}
+
+	// All destinations are updated within one transaction, so that either
+	// all of them are updated or none of them.
+	t := e.transaction(o.Destination, filepath.Join(tmp, "backup"))
+
+	for _, g := range groups(e.targets) {
+		var src []string
+		for _, i := range g {
+			src = append(src, dsts[i])
+		}
+
+		err := e.install(t, src, e.targets[g[0]].Destination, o.Prune)
+		if err != nil {
+			rer := t.rollback()
+			if rer != nil {
+				return tracer.Maskf(rollbackFailedError, "%s after %s", rer, err)
+			}
+
+			return tracer.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// job is a single command of a single target. Every command is executed in
+// its own temporary destination, so that the files it generated can be told
+// apart from the files generated by any other command. Only that way the
+// generated files of every command can be cached on their own.
+type job struct {
+	command   generate.Command
+	directory string
+	inputs    []generate.File
+	target    int
+}
+
+// build generates the code of all queued targets into the given temporary
+// directory and returns the temporary destination of every target, which are
+// indexed the same way the queued targets are. Commands are only executed if
+// their generated files cannot be restored from the cache.
+func (e *Engine) build(ctx context.Context, tmp string, o Options) ([]string, error) {
+	dsts := temporary(tmp, e.targets)
+
+	var p Plan
+	var l []job
+	for i, t := range e.targets {
+		g, err := t.Generator(dsts[i])
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		fil, err := g.Files()
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		cmd, err := g.Commands()
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		stg, err := stage(g)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		p.Files = append(p.Files, fil...)
+
+		for j, c := range cmd {
+			d := filepath.Join(tmp, "cmd", strconv.Itoa(i), strconv.Itoa(j))
+
+			// Every command is executed in its own temporary destination,
+			// which is why the paths of the target's temporary destination
+			// are rewritten accordingly.
+			c.Arguments = append([]string(nil), c.Arguments...)
+			for k, a := range c.Arguments {
+				c.Arguments[k] = retarget(a, dsts[i], d)
+			}
+			c.Directory = retarget(c.Directory, dsts[i], d)
+			c.Plugins = o.Plugins
+
+			// Generators may stage inputs for their own commands, which is
+			// why every command gets its own copy of the staged files it
+			// reads. Staged files are ignored when collecting the generated
+			// files of the command, so that they are never installed into
+			// the actual destination.
+			xs, err := e.needs(cmd[j], stg)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			for k := range xs {
+				xs[k].Path = retarget(xs[k].Path, dsts[i], d)
+			}
+
+			p.Files = append(p.Files, xs...)
+
+			l = append(l, job{command: c, directory: d, inputs: xs, target: i})
+		}
+	}
+
+	err := e.write(p.Files)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	ids := map[string]string{}
+
+	var key []string
+	var hit []*entry
+	for _, j := range l {
+		var k string
+		var h *entry
+		if o.Cache != "" {
+			k, err = e.key(j, ids)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			h, err = e.lookup(o.Cache, k)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+		}
+
+		key = append(key, k)
+		hit = append(hit, h)
+
+		if h == nil {
+			p.Commands = append(p.Commands, j.command)
+		}
+	}
+
+	err = e.execute(ctx, p.Commands, o)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	for i, j := range l {
+		h := hit[i]
+		if h == nil {
+			h, err = e.collect(j)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			if o.Cache != "" {
+				err := e.store(o.Cache, key[i], *h)
+				if err != nil {
+					return nil, tracer.Mask(err)
+				}
+			}
+		}
+
+		err := e.restore(*h, dsts[j.target])
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	if o.Cache != "" {
+		err := e.expire(o.Cache)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	return dsts, nil
+}
+
+// write writes the given files, e.g. the files generated by generators
+// themselves.
+func (e *Engine) write(files []generate.File) error {
+	for _, f := range files {
+		// The generated files may define arbitrary file paths on the file
+		// system. In order to be super save we simply ensure that the
+		// directory in which the generated file is supposed to be written
+		// to exists.
+		err := e.fileSystem.MkdirAll(filepath.Dir(f.Path), os.ModePerm)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		err = afero.WriteFile(e.fileSystem, f.Path, f.Bytes, 0600)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// execute creates the directories of the given commands and executes them.
+// The diagnostics of all failed commands are written to the output in the
+// configured format.
+func (e *Engine) execute(ctx context.Context, cmds []generate.Command, o Options) error {
+	// The gRPC tooling is not particularly prudent with file path and file
+	// system management. We need to ensure the configured directory structure
+	// in advance so that the gRPC tooling can generate the language specific
+	// code into that.
+	for _, c := range cmds {
+		err := e.fileSystem.MkdirAll(c.Directory, os.ModePerm)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	res := e.run(ctx, cmds, o.Jobs, o.Timeout)
+
+	// Cancelled executions do not report any diagnostic, since the commands
+	// did not fail on their own. Their partial output is removed together
+	// with the temporary destinations.
+	if ctx.Err() != nil {
+		return tracer.Maskf(executionCancelledError, "%s", ctx.Err())
+	}
+
+	// The results are reported in the order of the plan, regardless of the
+	// order the commands finished in, so that the output of every execution
+	// is deterministic.
+	var dia []diagnostic.Diagnostic
+	var fai int
+	for i, r := range res {
+		if r.err != nil {
+			// Commands which could not even be started, e.g. because protoc
+			// is not installed, do not have any output to report.
+			out := r.out
+			if len(out) == 0 {
+				out = []byte(r.err.Error())
+			}
+
+			dia = append(dia, diagnostic.Parse(cmds[i].String(), out)...)
+			fai++
+			continue
+		}
+
+		if len(r.out) != 0 {
+			_, err := fmt.Fprintf(e.output, "%s\n%s", cmds[i].String(), r.out)
+			if err != nil {
+				return tracer.Mask(err)
+			}
+		}
+	}
+
+	if fai != 0 {
+		err := writeDiagnostics(e.output, diagnostic.Sort(dia), o.Format)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		return tracer.Maskf(commandExecutionFailedError, "%d of %d commands failed", fai, len(res))
+	}
+
+	return nil
+}
+
+// result is the outcome of a single executed command.
+type result struct {
+	err error
+	out []byte
+}
+
+// run executes the given commands concurrently using at most the given number
+// of workers. Every command is cancelled once the given timeout elapsed,
+// unless the timeout is 0. Once ctx is cancelled, the remaining commands are
+// not executed anymore. The returned results are indexed the same way the
+// given commands are.
+func (e *Engine) run(ctx context.Context, cmds []generate.Command, jobs int, timeout time.Duration) []result {
+	res := make([]result, len(cmds))
+
+	ind := make(chan int)
+	go func() {
+		defer close(ind)
+		for i := range cmds {
+			ind <- i
+		}
+	}()
+
+	var w sync.WaitGroup
+	for j := 0; j < jobs; j++ {
+		w.Add(1)
+		go func() {
+			defer w.Done()
+			for i := range ind {
+				if ctx.Err() != nil {
+					res[i] = result{err: ctx.Err()}
+					continue
+				}
+
+				res[i] = e.one(ctx, cmds[i], timeout)
+			}
+		}()
+	}
+
+	w.Wait()
+
+	return res
+}
+
+// one executes the given command and reports commands exceeding the given
+// timeout the same way protoc reports its own failures.
+func (e *Engine) one(ctx context.Context, c generate.Command, timeout time.Duration) result {
+	if timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	out, err := e.executor.Execute(ctx, c)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		out = append(out, []byte(fmt.Sprintf("command timed out after %s\n", timeout))...)
+	}
+
+	return result{err: err, out: out}
+}
+
+// plan returns the plan of all queued targets for the given destinations,
+// which are indexed the same way the queued targets are.
+func (e *Engine) plan(dsts []string) (Plan, error) {
+	var gens []generate.Interface
+	for i, t := range e.targets {
+		g, err := t.Generator(dsts[i])
+		if err != nil {
+			return Plan{}, tracer.Mask(err)
+		}
+
+		gens = append(gens, g)
+	}
+
+	var p Plan
+	{
+		for _, g := range gens {
+			l, err := g.Files()
+			if err != nil {
+				return Plan{}, tracer.Mask(err)
+			}
+
+			p.Files = append(p.Files, l...)
+		}
+
+		for _, g := range gens {
+			l, err := stage(g)
+			if err != nil {
+				return Plan{}, tracer.Mask(err)
+			}
+
+			p.Staged = append(p.Staged, l...)
+		}
+
+		for _, g := range gens {
+			l, err := g.Commands()
+			if err != nil {
+				return Plan{}, tracer.Mask(err)
+			}
+
+			p.Commands = append(p.Commands, l...)
+		}
+	}
+
+	return p, nil
+}
+
+// stage returns the files the given generator stages for its own commands, if
+// any.
+func stage(g generate.Interface) ([]generate.File, error) {
+	s, ok := g.(generate.Stager)
+	if !ok {
+		return nil, nil
+	}
+
+	l, err := s.Stage()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	return l, nil
+}
+
+// needs returns the staged files the given command reads, which are the staged
+// schema files given as arguments and all of their transitive imports. Staged
+// files may also be imported by schema files which are not staged themselves,
+// which is why the staged files are looked up on top of the file system.
+func (e *Engine) needs(c generate.Command, staged []generate.File) ([]generate.File, error) {
+	if len(staged) == 0 {
+		return nil, nil
+	}
+
+	fs := afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(e.fileSystem), afero.NewMemMapFs())
+	for _, f := range staged {
+		err := fs.MkdirAll(filepath.Dir(f.Path), os.ModePerm)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		err = afero.WriteFile(fs, f.Path, f.Bytes, 0600)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	l, err := inputs(fs, c)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	m := map[string]bool{}
+	for _, p := range l {
+		m[p] = true
+	}
+
+	var x []generate.File
+	for _, f := range staged {
+		if m[filepath.Clean(f.Path)] {
+			x = append(x, f)
+		}
+	}
+
+	return x, nil
+}
+
+// retarget replaces the given directory within s, if it is followed by a path
+// separator or the end of s, e.g. the temporary destination of a target
+// within the output directory of a protoc argument.
+func retarget(s string, from string, to string) string {
+	var b strings.Builder
+
+	for {
+		i := strings.Index(s, from)
+		if i == -1 {
+			break
+		}
+
+		r := s[i+len(from):]
+		if r == "" || r[0] == filepath.Separator {
+			b.WriteString(s[:i] + to)
+		} else {
+			b.WriteString(s[:i+len(from)])
+		}
+
+		s = r
+	}
+
+	b.WriteString(s)
+
+	return b.String()
+}
+
+// groups returns the indices of all targets grouped by their destinations,
+// since multiple targets may generate code into the same destination. Groups
+// are ordered by their first target.
+func groups(targets []Target) [][]int {
+	var l [][]int
+
+	m := map[string]int{}
+	for i, t := range targets {
+		d := filepath.Clean(t.Destination)
+
+		j, ok := m[d]
+		if !ok {
+			j = len(l)
+			m[d] = j
+			l = append(l, nil)
+		}
+
+		l[j] = append(l[j], i)
+	}
+
+	return l
+}
+
+// temporary returns one temporary destination within tmp for every given
+// target. Every target gets its own temporary destination, because
+// destinations may be absolute or point to parent directories.
+func temporary(tmp string, targets []Target) []string {
+	var l []string
+	for i := range targets {
+		l = append(l, filepath.Join(tmp, strconv.Itoa(i)))
+	}
+
+	return l
+}
@@ -0,0 +1,1403 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/logger/fake"
+
+	"github.com/xh3b4sd/pag/pkg/executor"
+	executorfake "github.com/xh3b4sd/pag/pkg/executor/fake"
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_Engine_Execute_DryRun tests the rendering of generation plans. The
+// tests here ensure that dry runs print all commands, files and staged files
+// of all added generators in the configured format.
+//
+//	go test ./pkg/engine -run Test_Engine_Execute_DryRun -update
+func Test_Engine_Execute_DryRun(t *testing.T) {
+	testCases := []struct {
+		gen []generate.Interface
+		frm string
+	}{
+		// Case 0 ensures that empty plans are rendered as text.
+		{
+			gen: nil,
+			frm: FormatText,
+		},
+		// Case 1 ensures that empty plans are rendered as JSON.
+		{
+			gen: nil,
+			frm: FormatJSON,
+		},
+		// Case 2 ensures that the commands and files of multiple generators
+		// are rendered as text.
+		{
+			gen: []generate.Interface{
+				testGenerator{
+					cmds: []generate.Command{
+						{Binary: "protoc", Arguments: []string{"--go_out=pkg/pbf/user/", "--proto_path=.", "pbf/user/api.proto"}, Directory: "pkg/pbf/user"},
+					},
+				},
+				testGenerator{
+					cmds: []generate.Command{
+						{Binary: "protoc", Arguments: []string{"--js_out=src", "--proto_path=.", "pbf/user/api.proto"}, Directory: "src"},
+					},
+					files: []generate.File{
+						{Bytes: []byte("foo"), Path: "src/index.ts"},
+					},
+				},
+			},
+			frm: FormatText,
+		},
+		// Case 3 ensures that the commands and files of multiple generators
+		// are rendered as JSON.
+		{
+			gen: []generate.Interface{
+				testGenerator{
+					cmds: []generate.Command{
+						{Binary: "protoc", Arguments: []string{"--go_out=pkg/pbf/user/", "--proto_path=.", "pbf/user/api.proto"}, Directory: "pkg/pbf/user"},
+					},
+				},
+				testGenerator{
+					cmds: []generate.Command{
+						{Binary: "protoc", Arguments: []string{"--js_out=src", "--proto_path=.", "pbf/user/api.proto"}, Directory: "src"},
+					},
+					files: []generate.File{
+						{Bytes: []byte("foo"), Path: "src/index.ts"},
+					},
+				},
+			},
+			frm: FormatJSON,
+		},
+		// Case 4 ensures that staged files are rendered as text, marked as
+		// staged.
+		{
+			gen: []generate.Interface{
+				testStager{
+					testGenerator: testGenerator{
+						cmds: []generate.Command{
+							{Binary: "protoc", Arguments: []string{"--java_out=src/main/java/", "--proto_path=src/main/java/.pag/proto", "src/main/java/.pag/proto/pbf/user/api.proto"}, Directory: "src/main/java"},
+						},
+					},
+					stage: []generate.File{
+						{Bytes: []byte("foo"), Path: "src/main/java/.pag/proto/pbf/user/api.proto"},
+					},
+				},
+			},
+			frm: FormatText,
+		},
+		// Case 5 ensures that staged files are rendered as JSON.
+		{
+			gen: []generate.Interface{
+				testStager{
+					testGenerator: testGenerator{
+						cmds: []generate.Command{
+							{Binary: "protoc", Arguments: []string{"--java_out=src/main/java/", "--proto_path=src/main/java/.pag/proto", "src/main/java/.pag/proto/pbf/user/api.proto"}, Directory: "src/main/java"},
+						},
+					},
+					stage: []generate.File{
+						{Bytes: []byte("foo"), Path: "src/main/java/.pag/proto/pbf/user/api.proto"},
+					},
+				},
+			},
+			frm: FormatJSON,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var b bytes.Buffer
+
+			var e *Engine
+			{
+				c := Config{
+					Executor:   mustExecutor(),
+					FileSystem: afero.NewOsFs(),
+					Logger:     fake.New(),
+					Output:     &b,
+				}
+
+				e, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, g := range tc.gen {
+				e.Add(mustTarget(g))
+			}
+
+			err = e.Execute(context.Background(), Options{DryRun: true, Format: tc.frm})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := b.Bytes()
+
+			p := filepath.Join("testdata/plan", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Engine_Execute_Check tests the comparison of generated code with the
+// code in the actual destination. The tests here ensure that only differing
+// generated files are reported as unified diffs.
+//
+//	go test ./pkg/engine -run Test_Engine_Execute_Check -update
+func Test_Engine_Execute_Check(t *testing.T) {
+	testCases := []struct {
+		dst map[string]string
+		gen map[string]string
+		err func(error) bool
+	}{
+		// Case 0 ensures that up to date code does not cause an error.
+		{
+			dst: map[string]string{
+				"index.ts":  "foo\n",
+				"custom.ts": "bar\n",
+			},
+			gen: map[string]string{
+				"index.ts": "foo\n",
+			},
+			err: nil,
+		},
+		// Case 1 ensures that changed and missing files are reported, while
+		// files not generated are ignored.
+		{
+			dst: map[string]string{
+				"index.ts":  "foo\n",
+				"custom.ts": "bar\n",
+			},
+			gen: map[string]string{
+				"index.ts":     "baz\n",
+				"pbf/index.ts": "foo\n",
+			},
+			err: IsOutOfDate,
+		},
+		// Case 2 ensures that stale files recorded in the manifest are
+		// reported as deleted files.
+		{
+			dst: map[string]string{
+				"index.ts":           "foo\n",
+				"old.ts":             "bar\n",
+				".pag/manifest.json": `{"files":["index.ts","old.ts"]}`,
+			},
+			gen: map[string]string{
+				"index.ts": "foo\n",
+			},
+			err: IsOutOfDate,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			dst := t.TempDir()
+			for p, s := range tc.dst {
+				mustWriteFile(filepath.Join(dst, p), s)
+			}
+
+			var b bytes.Buffer
+
+			var e *Engine
+			{
+				c := Config{
+					Executor:   mustExecutor(),
+					FileSystem: afero.NewOsFs(),
+					Logger:     fake.New(),
+					Output:     &b,
+				}
+
+				e, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			e.Add(mustFileTarget(dst, tc.gen))
+
+			err = e.Execute(context.Background(), Options{Check: true, Format: FormatText, Jobs: 1})
+			if tc.err == nil && err != nil {
+				t.Fatal(err)
+			}
+			if tc.err != nil && !tc.err(err) {
+				t.Fatalf("expected error got %#v", err)
+			}
+
+			// The temporary destination is random, which is why it is
+			// replaced with a stable name within the rendered diffs.
+			actual := bytes.ReplaceAll(b.Bytes(), []byte(dst), []byte("dst"))
+
+			p := filepath.Join("testdata/check", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Engine_Execute_Prune tests the handling of stale files, which were
+// generated before but are not generated anymore. The tests here ensure that
+// stale files are tracked in the manifest of the destination and only listed
+// or deleted according to the configured prune mode. The golden files contain
+// the output of the second execution followed by the files remaining in the
+// destination.
+//
+//	go test ./pkg/engine -run Test_Engine_Execute_Prune -update
+func Test_Engine_Execute_Prune(t *testing.T) {
+	testCases := []struct {
+		fst map[string]string
+		snd map[string]string
+		prn string
+	}{
+		// Case 0 ensures that stale files are kept and remain in the manifest
+		// by default.
+		{
+			fst: map[string]string{
+				"index.ts":         "foo\n",
+				"pbf/user/user.ts": "bar\n",
+			},
+			snd: map[string]string{
+				"index.ts": "foo\n",
+			},
+			prn: "",
+		},
+		// Case 1 ensures that stale files are only listed in safe mode.
+		{
+			fst: map[string]string{
+				"index.ts":         "foo\n",
+				"pbf/user/user.ts": "bar\n",
+			},
+			snd: map[string]string{
+				"index.ts": "foo\n",
+			},
+			prn: PruneList,
+		},
+		// Case 2 ensures that stale files and their empty parent directories
+		// are deleted.
+		{
+			fst: map[string]string{
+				"index.ts":         "foo\n",
+				"pbf/post/post.ts": "baz\n",
+				"pbf/user/user.ts": "bar\n",
+			},
+			snd: map[string]string{
+				"index.ts":         "foo\n",
+				"pbf/user/user.ts": "bar\n",
+			},
+			prn: PruneDelete,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			dst := t.TempDir()
+
+			// Files not generated by pag must never be considered stale.
+			mustWriteFile(filepath.Join(dst, "pbf/user/custom.ts"), "custom\n")
+
+			var b bytes.Buffer
+
+			var e *Engine
+			{
+				c := Config{
+					Executor:   mustExecutor(),
+					FileSystem: afero.NewOsFs(),
+					Logger:     fake.New(),
+					Output:     &b,
+				}
+
+				e, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			e.Add(mustFileTarget(dst, tc.fst))
+			err = e.Execute(context.Background(), Options{Format: FormatText, Jobs: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b.Reset()
+
+			e.Add(mustFileTarget(dst, tc.snd))
+			err = e.Execute(context.Background(), Options{Format: FormatText, Jobs: 1, Prune: tc.prn})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = filepath.Walk(dst, func(p string, i os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+
+				rel, err := filepath.Rel(dst, p)
+				if err != nil {
+					return err
+				}
+
+				if i.IsDir() {
+					b.WriteString(rel + "/\n")
+				} else {
+					b.WriteString(rel + "\n")
+				}
+
+				if rel == Manifest {
+					c, err := ioutil.ReadFile(p)
+					if err != nil {
+						return err
+					}
+
+					b.Write(c)
+				}
+
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := bytes.ReplaceAll(b.Bytes(), []byte(dst), []byte("dst"))
+
+			p := filepath.Join("testdata/prune", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Engine_Execute_Jobs tests the concurrent execution of commands. The
+// tests here ensure that the output and errors of all commands are reported
+// in the order of the plan, regardless of the order the commands finished
+// in. The golden files contain the output of the execution followed by the
+// returned error, if any.
+//
+//	go test ./pkg/engine -run Test_Engine_Execute_Jobs -update
+func Test_Engine_Execute_Jobs(t *testing.T) {
+	testCases := []struct {
+		cmds []generate.Command
+		jobs int
+	}{
+		// Case 0 ensures that the output of successful commands is reported
+		// in order when executing commands sequentially.
+		{
+			cmds: []generate.Command{
+				mustShell("echo foo"),
+				mustShell("true"),
+				mustShell("echo bar"),
+			},
+			jobs: 1,
+		},
+		// Case 1 ensures that the output of successful commands is reported
+		// in order when later commands finish first.
+		{
+			cmds: []generate.Command{
+				mustShell("sleep 0.2; echo foo"),
+				mustShell("sleep 0.1; echo bar"),
+				mustShell("echo baz"),
+			},
+			jobs: 3,
+		},
+		// Case 2 ensures that all failed commands are reported in order
+		// without aborting the remaining commands.
+		{
+			cmds: []generate.Command{
+				mustShell("sleep 0.2; echo foo; exit 1"),
+				mustShell("echo bar"),
+				mustShell("echo baz; exit 1"),
+			},
+			jobs: 2,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var b bytes.Buffer
+
+			var e *Engine
+			{
+				c := Config{
+					Executor:   mustExecutor(),
+					FileSystem: afero.NewOsFs(),
+					Logger:     fake.New(),
+					Output:     &b,
+				}
+
+				e, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			e.Add(Target{
+				Destination: t.TempDir(),
+				Generator: func(d string) (generate.Interface, error) {
+					var cmds []generate.Command
+					for _, c := range tc.cmds {
+						c.Directory = filepath.Join(d, c.Directory)
+						cmds = append(cmds, c)
+					}
+
+					return testGenerator{cmds: cmds}, nil
+				},
+			})
+
+			err = e.Execute(context.Background(), Options{Format: FormatText, Jobs: tc.jobs})
+			if err != nil {
+				b.WriteString("\n" + err.Error() + "\n")
+			}
+
+			actual := b.Bytes()
+
+			p := filepath.Join("testdata/jobs", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Engine_Execute_Stage tests the handling of files generators stage for
+// their own commands. The tests here ensure that every command can read the
+// staged files it needs, including their transitive imports and the staged
+// imports of schema files which are not staged themselves, but no other staged
+// file, and that staged files are never installed into the actual
+// destination.
+func Test_Engine_Execute_Stage(t *testing.T) {
+	var err error
+
+	dst := t.TempDir()
+	src := t.TempDir()
+
+	mustWriteFile(filepath.Join(src, "pbf/note/api.proto"), "syntax = \"proto3\";\n\nimport \"google/api/http.proto\";\n")
+
+	tar := Target{
+		Destination: dst,
+		Generator: func(d string) (generate.Interface, error) {
+			g := testStager{
+				testGenerator: testGenerator{
+					cmds: []generate.Command{
+						{Binary: "protoc", Arguments: []string{"--java_out=" + d, "--proto_path=" + d + "/.pag/proto", "pbf/user/api.proto"}, Directory: d},
+						{Binary: "protoc", Arguments: []string{"--java_out=" + d, "--proto_path=" + d + "/.pag/proto", "pbf/post/api.proto"}, Directory: d},
+						{Binary: "protoc", Arguments: []string{"--java_out=" + d, "--proto_path=" + src, "--proto_path=" + d + "/.pag/proto", "pbf/note/api.proto"}, Directory: d},
+					},
+					files: []generate.File{
+						{Bytes: []byte("foo"), Path: filepath.Join(d, "index.txt")},
+					},
+				},
+				stage: []generate.File{
+					{Bytes: []byte("syntax = \"proto3\";\n\nimport \"pbf/user/type.proto\";\n"), Path: filepath.Join(d, ".pag/proto/pbf/user/api.proto")},
+					{Bytes: []byte("syntax = \"proto3\";\n"), Path: filepath.Join(d, ".pag/proto/pbf/user/type.proto")},
+					{Bytes: []byte("syntax = \"proto3\";\n"), Path: filepath.Join(d, ".pag/proto/pbf/post/api.proto")},
+					{Bytes: []byte("syntax = \"proto3\";\n"), Path: filepath.Join(d, ".pag/proto/google/api/http.proto")},
+				},
+			}
+
+			return g, nil
+		},
+	}
+
+	x := &testExecutor{Interface: executorfake.NewProtoc(afero.NewOsFs())}
+
+	var e *Engine
+	{
+		c := Config{
+			Executor:   x,
+			FileSystem: afero.NewOsFs(),
+			Logger:     fake.New(),
+			Output:     ioutil.Discard,
+		}
+
+		e, err = New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e.Add(tar)
+
+	err = e.Execute(context.Background(), Options{Format: FormatText, Jobs: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		expected := [][]string{
+			{".pag/proto/pbf/user/api.proto", ".pag/proto/pbf/user/type.proto"},
+			{".pag/proto/pbf/post/api.proto"},
+			{".pag/proto/google/api/http.proto"},
+		}
+
+		if !reflect.DeepEqual(expected, x.inputs) {
+			t.Fatalf("\n\n%s\n", cmp.Diff(expected, x.inputs))
+		}
+	}
+
+	var actual []string
+	for p, s := range mustSnapshot(dst) {
+		if s != "dir" {
+			actual = append(actual, strings.TrimPrefix(p, dst+"/"))
+		}
+	}
+
+	sort.Strings(actual)
+
+	expected := []string{
+		".pag/manifest.json",
+		"api.java.pb",
+		"index.txt",
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expected, actual))
+	}
+}
+
+// Test_Engine_Execute_Diagnostics tests the reporting of failed commands.
+// The tests here ensure that the output of failed commands is rendered as
+// diagnostics grouped by schema file in the configured format.
+//
+//	go test ./pkg/engine -run Test_Engine_Execute_Diagnostics -update
+func Test_Engine_Execute_Diagnostics(t *testing.T) {
+	testCases := []struct {
+		frm string
+	}{
+		// Case 0 ensures that diagnostics are rendered as text.
+		{
+			frm: FormatText,
+		},
+		// Case 1 ensures that diagnostics are rendered as JSON.
+		{
+			frm: FormatJSON,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var b bytes.Buffer
+
+			var e *Engine
+			{
+				c := Config{
+					Executor:   mustExecutor(),
+					FileSystem: afero.NewOsFs(),
+					Logger:     fake.New(),
+					Output:     &b,
+				}
+
+				e, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Protoc reports the same problems for every plugin, which is
+			// why both commands report the same syntax error.
+			cmds := []generate.Command{
+				mustShell(`echo 'pbf/user/api.proto:9:17: Expected ";".' >&2; exit 1`),
+				mustShell(`echo 'pbf/user/api.proto:9:17: Expected ";".' >&2; echo 'pbf/post/delete.proto: File not found.' >&2; exit 1`),
+				mustShell(`echo '--go_out: protoc-gen-go: Plugin failed with status code 1.' >&2; exit 1`),
+			}
+
+			e.Add(Target{
+				Destination: t.TempDir(),
+				Generator: func(d string) (generate.Interface, error) {
+					return testGenerator{cmds: cmds}, nil
+				},
+			})
+
+			err = e.Execute(context.Background(), Options{Format: tc.frm, Jobs: 1})
+			if !IsCommandExecutionFailed(err) {
+				t.Fatalf("expected commandExecutionFailedError got %#v", err)
+			}
+
+			actual := b.Bytes()
+
+			p := filepath.Join("testdata/diagnostics", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Engine_Execute_Cancel tests the cancellation of commands. The tests
+// here ensure that commands exceeding their timeout are reported as failed,
+// that cancelled executions do not execute any further command and that
+// destinations are left untouched in both cases.
+func Test_Engine_Execute_Cancel(t *testing.T) {
+	testCases := []struct {
+		ctx func() (context.Context, context.CancelFunc)
+		tim time.Duration
+		out string
+		err func(error) bool
+	}{
+		// Case 0 ensures that commands exceeding their timeout are reported
+		// as failed.
+		{
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+			tim: 100 * time.Millisecond,
+			out: "sh -c exec sleep 5\n  command timed out after 100ms\n",
+			err: IsCommandExecutionFailed,
+		},
+		// Case 1 ensures that cancelled executions stop running commands.
+		{
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 100*time.Millisecond)
+			},
+			tim: 0,
+			out: "",
+			err: IsExecutionCancelled,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var b bytes.Buffer
+
+			var e *Engine
+			{
+				c := Config{
+					Executor:   mustExecutor(),
+					FileSystem: afero.NewOsFs(),
+					Logger:     fake.New(),
+					Output:     &b,
+				}
+
+				e, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			dst := t.TempDir()
+
+			e.Add(Target{
+				Destination: dst,
+				Generator: func(d string) (generate.Interface, error) {
+					g := testGenerator{
+						cmds: []generate.Command{
+							{Binary: "sh", Arguments: []string{"-c", "exec sleep 5"}, Directory: d},
+						},
+						files: []generate.File{
+							{Bytes: []byte("foo"), Path: filepath.Join(d, "index.ts")},
+						},
+					}
+
+					return g, nil
+				},
+			})
+
+			ctx, cancel := tc.ctx()
+			defer cancel()
+
+			err = e.Execute(ctx, Options{Format: FormatText, Jobs: 1, Timeout: tc.tim})
+			if !tc.err(err) {
+				t.Fatalf("expected error got %#v", err)
+			}
+
+			if b.String() != tc.out {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.out, b.String()))
+			}
+
+			l, err := ioutil.ReadDir(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(l) != 0 {
+				t.Fatalf("expected destination to be empty got %d files", len(l))
+			}
+		})
+	}
+}
+
+// Test_Engine_Execute_Cache tests the caching of generated code. The tests
+// here ensure that commands are only executed again if their schema files,
+// including transitively imported schema files, changed since the previous
+// execution. The golden files contain the commands of the second execution
+// followed by the files of the destination.
+//
+//	go test ./pkg/engine -run Test_Engine_Execute_Cache -update
+func Test_Engine_Execute_Cache(t *testing.T) {
+	testCases := []struct {
+		chg map[string]string
+	}{
+		// Case 0 ensures that no command is executed again if nothing
+		// changed.
+		{
+			chg: nil,
+		},
+		// Case 1 ensures that only the commands of changed schema files are
+		// executed again.
+		{
+			chg: map[string]string{
+				"post/delete.proto": "syntax = \"proto3\";\n\nmessage DeleteI { string id = 1; }\n",
+			},
+		},
+		// Case 2 ensures that the commands of schema files importing changed
+		// schema files are executed again.
+		{
+			chg: map[string]string{
+				"shared/type.proto": "syntax = \"proto3\";\n\nmessage Type { string id = 1; }\n",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			src := t.TempDir()
+			dst := t.TempDir()
+			cch := t.TempDir()
+
+			mustWriteFile(filepath.Join(src, "user/api.proto"), "syntax = \"proto3\";\n\nimport \"shared/type.proto\";\n\nservice API {}\n")
+			mustWriteFile(filepath.Join(src, "post/delete.proto"), "syntax = \"proto3\";\n\nmessage DeleteI {}\n")
+			mustWriteFile(filepath.Join(src, "shared/type.proto"), "syntax = \"proto3\";\n\nmessage Type {}\n")
+
+			tar := Target{
+				Destination: dst,
+				Generator: func(d string) (generate.Interface, error) {
+					g := testGenerator{
+						cmds: []generate.Command{
+							{Binary: "protoc", Arguments: []string{"--go_out=" + d + "/post/", "--proto_path=" + src, "post/delete.proto"}, Directory: d + "/post/"},
+							{Binary: "protoc", Arguments: []string{"--go_out=" + d + "/user/", "--proto_path=" + src, "user/api.proto"}, Directory: d + "/user/"},
+						},
+					}
+
+					return g, nil
+				},
+			}
+
+			var x *executorfake.Executor
+			for j := 0; j < 2; j++ {
+				if j == 1 {
+					for p, s := range tc.chg {
+						mustWriteFile(filepath.Join(src, p), s)
+					}
+				}
+
+				x = executorfake.NewProtoc(afero.NewOsFs())
+
+				var e *Engine
+				{
+					c := Config{
+						Executor:   x,
+						FileSystem: afero.NewOsFs(),
+						Logger:     fake.New(),
+						Output:     ioutil.Discard,
+					}
+
+					e, err = New(c)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				e.Add(tar)
+
+				err = e.Execute(context.Background(), Options{Cache: cch, Format: FormatText, Jobs: 1})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var b bytes.Buffer
+
+			for _, c := range x.Commands() {
+				b.WriteString(c.String() + "\n")
+			}
+
+			b.WriteString("\n")
+
+			err = filepath.Walk(dst, func(p string, i os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+
+				if i.IsDir() {
+					return nil
+				}
+
+				c, err := ioutil.ReadFile(p)
+				if err != nil {
+					return err
+				}
+
+				b.WriteString("==> " + p + "\n")
+				b.Write(c)
+
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := b.Bytes()
+			actual = bytes.ReplaceAll(actual, []byte(src), []byte("src"))
+			actual = bytes.ReplaceAll(actual, []byte(dst), []byte("dst"))
+			actual = regexp.MustCompile(`[^\s=:]*/pag-generate-[0-9]+`).ReplaceAll(actual, []byte("tmp"))
+
+			p := filepath.Join("testdata/cache", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Engine_Execute_Cache_Expiry tests the removal of cache entries. The
+// tests here ensure that entries not used within the expiry duration are
+// removed, while recently used entries are kept.
+func Test_Engine_Execute_Cache_Expiry(t *testing.T) {
+	var err error
+
+	dst := t.TempDir()
+	cch := t.TempDir()
+
+	old := filepath.Join(cch, "aa", strings.Repeat("a", 64)+".json")
+	cur := filepath.Join(cch, "bb", strings.Repeat("b", 64)+".json")
+
+	mustWriteFile(old, `{"files":[]}`)
+	mustWriteFile(cur, `{"files":[]}`)
+
+	{
+		m := time.Now().Add(-expiry - time.Hour)
+
+		err := os.Chtimes(old, m, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var e *Engine
+	{
+		c := Config{
+			Executor:   executorfake.NewProtoc(afero.NewOsFs()),
+			FileSystem: afero.NewOsFs(),
+			Logger:     fake.New(),
+			Output:     ioutil.Discard,
+		}
+
+		e, err = New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e.Add(mustFileTarget(dst, map[string]string{"index.txt": "foo"}))
+
+	err = e.Execute(context.Background(), Options{Cache: cch, Format: FormatText, Jobs: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be removed", old)
+	}
+	if _, err := os.Stat(cur); err != nil {
+		t.Fatalf("expected %s to be kept", cur)
+	}
+}
+
+// Test_Engine_Execute_Rollback tests that destinations are left intact if
+// generation fails. The tests here ensure that failed commands do not touch
+// destinations at all and that failures while installing generated files
+// restore all files changed so far.
+func Test_Engine_Execute_Rollback(t *testing.T) {
+	testCases := []struct {
+		cmd []generate.Command
+		gen map[string]string
+		err func(error) bool
+	}{
+		// Case 0 ensures that failed commands do not touch destinations.
+		{
+			cmd: []generate.Command{
+				mustShell("exit 1"),
+			},
+			gen: map[string]string{
+				"a/new.ts": "new\n",
+				"index.ts": "new\n",
+			},
+			err: IsCommandExecutionFailed,
+		},
+		// Case 1 ensures that failures while installing generated files
+		// restore replaced files and remove created files and directories.
+		{
+			gen: map[string]string{
+				"a/new.ts":    "new\n",
+				"index.ts":    "new\n",
+				"z/broken.ts": "new\n",
+			},
+			err: func(err error) bool { return err != nil && !IsRollbackFailed(err) },
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			dst := t.TempDir()
+
+			mustWriteFile(filepath.Join(dst, "index.ts"), "old\n")
+			mustWriteFile(filepath.Join(dst, "custom.ts"), "custom\n")
+			mustWriteFile(filepath.Join(dst, Manifest), `{"files":["index.ts"]}`)
+
+			expected := mustSnapshot(dst)
+
+			var e *Engine
+			{
+				c := Config{
+					Executor:   mustExecutor(),
+					FileSystem: failingFs{Fs: afero.NewOsFs(), fail: "broken"},
+					Logger:     fake.New(),
+					Output:     ioutil.Discard,
+				}
+
+				e, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			e.Add(Target{
+				Destination: dst,
+				Generator: func(d string) (generate.Interface, error) {
+					var files []generate.File
+					for _, p := range sortedKeys(tc.gen) {
+						files = append(files, generate.File{Bytes: []byte(tc.gen[p]), Path: filepath.Join(d, p)})
+					}
+
+					var cmds []generate.Command
+					for _, c := range tc.cmd {
+						c.Directory = filepath.Join(d, c.Directory)
+						cmds = append(cmds, c)
+					}
+
+					return testGenerator{cmds: cmds, files: files}, nil
+				},
+			})
+
+			err = e.Execute(context.Background(), Options{Format: FormatText, Jobs: 1})
+			if !tc.err(err) {
+				t.Fatalf("expected error got %#v", err)
+			}
+
+			actual := mustSnapshot(dst)
+
+			if !cmp.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(expected, actual))
+			}
+		})
+	}
+}
+
+// Test_Engine_Execute_Destination tests the installation of generated files
+// into another file system than the one commands are executed on. The tests
+// here run entirely in memory, using the fake executor as stand-in for
+// protoc, and ensure that generated files only end up within the destination
+// file system. The golden files contain all files of the destination file
+// system after the generation.
+//
+//	go test ./pkg/engine -run Test_Engine_Execute_Destination -update
+func Test_Engine_Execute_Destination(t *testing.T) {
+	testCases := []struct {
+		dst map[string]string
+		prn string
+	}{
+		// Case 0 ensures that the generated files of generators and commands
+		// are installed into an empty destination file system.
+		{
+			dst: nil,
+		},
+		// Case 1 ensures that stale files recorded in the manifest of the
+		// destination file system are pruned, while other files are kept.
+		{
+			dst: map[string]string{
+				"/dst/custom.ts":      "custom\n",
+				"/dst/post/old.go.pb": "old\n",
+				"/dst/" + Manifest:    `{"files":["post/old.go.pb"]}`,
+			},
+			prn: PruneDelete,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			fs := afero.NewMemMapFs()
+			dfs := afero.NewMemMapFs()
+
+			mustWriteMemFile(fs, "/src/user/api.proto", "syntax = \"proto3\";\n\nservice API {}\n")
+
+			for _, p := range sortedKeys(tc.dst) {
+				mustWriteMemFile(dfs, p, tc.dst[p])
+			}
+
+			var e *Engine
+			{
+				c := Config{
+					Executor:   executorfake.NewProtoc(fs),
+					FileSystem: fs,
+					Logger:     fake.New(),
+					Output:     ioutil.Discard,
+				}
+
+				e, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			e.Add(Target{
+				Destination: "/dst",
+				Generator: func(d string) (generate.Interface, error) {
+					g := testGenerator{
+						cmds: []generate.Command{
+							{Binary: "protoc", Arguments: []string{"--go_out=" + d + "/user/", "--proto_path=/src", "user/api.proto"}, Directory: d + "/user/"},
+						},
+						files: []generate.File{
+							{Bytes: []byte("index\n"), Path: filepath.Join(d, "index.ts")},
+						},
+					}
+
+					return g, nil
+				},
+			})
+
+			err = e.Execute(context.Background(), Options{Destination: dfs, Format: FormatText, Jobs: 1, Prune: tc.prn})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ok, err := afero.DirExists(fs, "/dst")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok {
+				t.Fatalf("expected /dst not to exist within the engine's file system")
+			}
+
+			var b bytes.Buffer
+
+			err = afero.Walk(dfs, "/", func(p string, i os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+
+				if i.IsDir() {
+					return nil
+				}
+
+				c, err := afero.ReadFile(dfs, p)
+				if err != nil {
+					return err
+				}
+
+				b.WriteString("==> " + p + "\n")
+				b.Write(c)
+
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := b.Bytes()
+
+			p := filepath.Join("testdata/destination", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
+
+func mustExecutor() executor.Interface {
+	e, err := executor.New(executor.Config{})
+	if err != nil {
+		panic(err)
+	}
+
+	return e
+}
+
+// mustSnapshot returns all directories and files within the given directory
+// together with the content of all files.
+func mustSnapshot(dir string) map[string]string {
+	m := map[string]string{}
+
+	err := filepath.Walk(dir, func(p string, i os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if i.IsDir() {
+			m[p] = "dir"
+			return nil
+		}
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		m[p] = i.Mode().String() + " " + string(b)
+
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+func mustShell(s string) generate.Command {
+	return generate.Command{
+		Binary:    "sh",
+		Arguments: []string{"-c", s},
+		Directory: "out",
+	}
+}
+
+func mustTarget(g generate.Interface) Target {
+	return Target{
+		Destination: "dst",
+		Generator: func(string) (generate.Interface, error) {
+			return g, nil
+		},
+	}
+}
+
+// mustFileTarget returns a target generating the given files into dst. The
+// given files map file paths relative to the destination to their content.
+func mustFileTarget(dst string, gen map[string]string) Target {
+	return Target{
+		Destination: dst,
+		Generator: func(d string) (generate.Interface, error) {
+			var files []generate.File
+			for _, p := range sortedKeys(gen) {
+				files = append(files, generate.File{Bytes: []byte(gen[p]), Path: filepath.Join(d, p)})
+			}
+
+			return testGenerator{files: files}, nil
+		},
+	}
+}
+
+func mustWriteMemFile(fs afero.Fs, p string, s string) {
+	err := afero.WriteFile(fs, p, []byte(s), 0600)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustWriteFile(p string, s string) {
+	err := os.MkdirAll(filepath.Dir(p), os.ModePerm)
+	if err != nil {
+		panic(err)
+	}
+
+	err = ioutil.WriteFile(p, []byte(s), 0600)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	var l []string
+	for k := range m {
+		l = append(l, k)
+	}
+
+	sort.Strings(l)
+
+	return l
+}
+
+// failingFs fails renaming files to paths containing fail, which is how
+// generated files are installed into their destinations.
+type failingFs struct {
+	afero.Fs
+	fail string
+}
+
+func (f failingFs) Rename(o string, n string) error {
+	if strings.Contains(n, f.fail) {
+		return errors.New("rename failed")
+	}
+
+	return f.Fs.Rename(o, n)
+}
+
+// testExecutor records the files within the directory of every executed
+// command, before executing the command.
+type testExecutor struct {
+	executor.Interface
+	inputs [][]string
+}
+
+func (x *testExecutor) Execute(ctx context.Context, c generate.Command) ([]byte, error) {
+	var l []string
+	for p, s := range mustSnapshot(c.Directory) {
+		if s != "dir" {
+			l = append(l, strings.TrimPrefix(p, c.Directory+"/"))
+		}
+	}
+
+	sort.Strings(l)
+
+	x.inputs = append(x.inputs, l)
+
+	return x.Interface.Execute(ctx, c)
+}
+
+type testGenerator struct {
+	cmds  []generate.Command
+	files []generate.File
+}
+
+func (g testGenerator) Commands() ([]generate.Command, error) {
+	return g.cmds, nil
+}
+
+func (g testGenerator) Files() ([]generate.File, error) {
+	return g.files, nil
+}
+
+type testStager struct {
+	testGenerator
+	stage []generate.File
+}
+
+func (g testStager) Stage() ([]generate.File, error) {
+	return g.stage, nil
+}
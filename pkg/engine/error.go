@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"errors"
+
+	"github.com/xh3b4sd/tracer"
+)
+
+var commandExecutionFailedError = &tracer.Error{
+	Kind: "commandExecutionFailedError",
+}
+
+func IsCommandExecutionFailed(err error) bool {
+	return errors.Is(err, commandExecutionFailedError)
+}
+
+var executionCancelledError = &tracer.Error{
+	Kind: "executionCancelledError",
+}
+
+func IsExecutionCancelled(err error) bool {
+	return errors.Is(err, executionCancelledError)
+}
+
+var invalidCacheError = &tracer.Error{
+	Kind: "invalidCacheError",
+}
+
+func IsInvalidCache(err error) bool {
+	return errors.Is(err, invalidCacheError)
+}
+
+var invalidConfigError = &tracer.Error{
+	Kind: "invalidConfigError",
+}
+
+func IsInvalidConfig(err error) bool {
+	return errors.Is(err, invalidConfigError)
+}
+
+var invalidManifestError = &tracer.Error{
+	Kind: "invalidManifestError",
+}
+
+func IsInvalidManifest(err error) bool {
+	return errors.Is(err, invalidManifestError)
+}
+
+var invalidOptionsError = &tracer.Error{
+	Kind: "invalidOptionsError",
+}
+
+func IsInvalidOptions(err error) bool {
+	return errors.Is(err, invalidOptionsError)
+}
+
+var outOfDateError = &tracer.Error{
+	Kind: "outOfDateError",
+}
+
+func IsOutOfDate(err error) bool {
+	return errors.Is(err, outOfDateError)
+}
+
+var rollbackFailedError = &tracer.Error{
+	Kind: "rollbackFailedError",
+}
+
+func IsRollbackFailed(err error) bool {
+	return errors.Is(err, rollbackFailedError)
+}
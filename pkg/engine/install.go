@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+)
+
+// files maps file paths relative to a destination to the location of the
+// respective generated file within a temporary destination.
+type files map[string]string
+
+func (f files) paths() []string {
+	var l []string
+	for k := range f {
+		l = append(l, k)
+	}
+
+	sort.Strings(l)
+
+	return l
+}
+
+// generated returns all files generated into the given temporary
+// destinations.
+func (e *Engine) generated(src []string) (files, error) {
+	f := files{}
+
+	for _, s := range src {
+		walkFunc := func(p string, i os.FileInfo, err error) error {
+			if err != nil {
+				return tracer.Mask(err)
+			}
+
+			if i.IsDir() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(s, p)
+			if err != nil {
+				return tracer.Mask(err)
+			}
+
+			f[rel] = p
+
+			return nil
+		}
+
+		// Targets without any generated file do not even create their
+		// temporary destination.
+		_, err := e.fileSystem.Stat(s)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		err = afero.Walk(e.fileSystem, s, walkFunc)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	return f, nil
+}
+
+// install copies all files generated into the given temporary destinations
+// into dst and updates the manifest of dst. Stale files are handled according
+// to the given prune mode. All changes are applied within the given
+// transaction, which also defines the file system of dst.
+func (e *Engine) install(t *transaction, src []string, dst string, prune string) error {
+	gen, err := e.generated(src)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	m, err := readManifest(t.fileSystem, dst)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	for _, f := range gen.paths() {
+		err := e.copyFile(t, gen[f], filepath.Join(dst, f))
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	var keep []string
+	for _, f := range stale(t.fileSystem, m.Files, gen, dst) {
+		switch prune {
+		case PruneDelete:
+			err := e.remove(t, dst, f)
+			if err != nil {
+				return tracer.Mask(err)
+			}
+		case PruneList:
+			_, err := fmt.Fprintf(e.output, "stale %s\n", filepath.Join(dst, f))
+			if err != nil {
+				return tracer.Mask(err)
+			}
+
+			keep = append(keep, f)
+		default:
+			keep = append(keep, f)
+		}
+	}
+
+	// Stale files not pruned remain in the manifest, so that they can still
+	// be pruned later on.
+	err = e.writeManifest(t, dst, manifest{Files: append(gen.paths(), keep...)})
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+func (e *Engine) copyFile(t *transaction, src string, dst string) error {
+	i, err := e.fileSystem.Stat(src)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	b, err := afero.ReadFile(e.fileSystem, src)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	err = t.write(dst, b, i.Mode().Perm())
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+)
+
+const (
+	// Internal is the directory within destinations where pag keeps its
+	// internal state.
+	Internal = ".pag"
+	// Manifest is the file within destinations recording all files pag
+	// generated into the destination.
+	Manifest = ".pag/manifest.json"
+)
+
+// manifest records all files generated into a destination, relative to the
+// destination, so that files generated before but not generated anymore can
+// be detected and pruned.
+type manifest struct {
+	Files []string `json:"files"`
+}
+
+// stale returns all files of the given manifest which are not generated
+// anymore but still exist within dst. Manifest entries pointing outside of
+// dst are never considered, so that manipulated manifests cannot cause the
+// removal of arbitrary files.
+func stale(fs afero.Fs, l []string, gen files, dst string) []string {
+	var s []string
+	for _, f := range l {
+		if !local(f) {
+			continue
+		}
+
+		_, ok := gen[f]
+		if ok {
+			continue
+		}
+
+		_, err := fs.Stat(filepath.Join(dst, f))
+		if err != nil {
+			continue
+		}
+
+		s = append(s, f)
+	}
+
+	sort.Strings(s)
+
+	return s
+}
+
+// local returns whether the given path is a relative path pointing into its
+// destination.
+func local(p string) bool {
+	if p == "" || filepath.IsAbs(p) {
+		return false
+	}
+
+	c := filepath.Clean(p)
+	if c == ".." || strings.HasPrefix(c, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	return c == p && c != Manifest
+}
+
+// remove deletes the given file within dst and all of its parent directories
+// within dst which became empty.
+func (e *Engine) remove(t *transaction, dst string, f string) error {
+	err := t.remove(filepath.Join(dst, f))
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	for d := filepath.Dir(f); d != "."; d = filepath.Dir(d) {
+		l, err := afero.ReadDir(t.fileSystem, filepath.Join(dst, d))
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		if len(l) != 0 {
+			break
+		}
+
+		err = t.rmdir(filepath.Join(dst, d))
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+func readManifest(fs afero.Fs, dst string) (manifest, error) {
+	b, err := afero.ReadFile(fs, filepath.Join(dst, Manifest))
+	if os.IsNotExist(err) {
+		return manifest{}, nil
+	} else if err != nil {
+		return manifest{}, tracer.Mask(err)
+	}
+
+	var m manifest
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return manifest{}, tracer.Maskf(invalidManifestError, "%s: %s", filepath.Join(dst, Manifest), err)
+	}
+
+	return m, nil
+}
+
+func (e *Engine) writeManifest(t *transaction, dst string, m manifest) error {
+	sort.Strings(m.Files)
+
+	if m.Files == nil {
+		m.Files = []string{}
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	err = t.write(filepath.Join(dst, Manifest), append(b, '\n'), 0600)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
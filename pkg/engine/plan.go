@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+// Plan is everything code generation is about to do. Plans can be rendered
+// as plain text and as JSON so that generation plans can be compared across
+// commits.
+type Plan struct {
+	Commands []generate.Command
+	Files    []generate.File
+	// Staged are the files generators stage for their own commands. Staged
+	// files only exist while their commands are executed and are never
+	// installed into any destination.
+	Staged []generate.File
+}
+
+type planJSON struct {
+	Commands []commandJSON `json:"commands"`
+	Files    []fileJSON    `json:"files"`
+	Staged   []fileJSON    `json:"staged"`
+}
+
+type commandJSON struct {
+	Binary    string   `json:"binary"`
+	Arguments []string `json:"arguments"`
+	Directory string   `json:"directory"`
+}
+
+type fileJSON struct {
+	Path string `json:"path"`
+}
+
+// Write renders the plan in the given format. The text format prints one
+// staged file path per line, marked as such, followed by one command per line
+// and one file path per line.
+//
+//	staged src/main/java/.pag/proto/pbf/user/api.proto
+//	protoc --experimental_allow_proto3_optional --java_out=src/main/java/ ...
+//	protoc --experimental_allow_proto3_optional --go_out=pkg/pbf/user/ ...
+//	src/index.ts
+func (p Plan) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		j := planJSON{
+			Commands: []commandJSON{},
+			Files:    []fileJSON{},
+			Staged:   []fileJSON{},
+		}
+
+		for _, c := range p.Commands {
+			j.Commands = append(j.Commands, commandJSON{Binary: c.Binary, Arguments: c.Arguments, Directory: c.Directory})
+		}
+
+		for _, f := range p.Files {
+			j.Files = append(j.Files, fileJSON{Path: f.Path})
+		}
+
+		for _, f := range p.Staged {
+			j.Staged = append(j.Staged, fileJSON{Path: f.Path})
+		}
+
+		b, err := json.MarshalIndent(j, "", "  ")
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	case FormatText:
+		for _, f := range p.Staged {
+			_, err := fmt.Fprintf(w, "staged %s\n", f.Path)
+			if err != nil {
+				return tracer.Mask(err)
+			}
+		}
+
+		for _, c := range p.Commands {
+			_, err := fmt.Fprintln(w, c.String())
+			if err != nil {
+				return tracer.Mask(err)
+			}
+		}
+
+		for _, f := range p.Files {
+			_, err := fmt.Fprintln(w, f.Path)
+			if err != nil {
+				return tracer.Mask(err)
+			}
+		}
+	default:
+		return tracer.Maskf(invalidOptionsError, "format must be %s or %s", FormatJSON, FormatText)
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+)
+
+// transaction applies changes to destinations in a way that allows to revert
+// all of them. Every file is replaced atomically by renaming a temporary file
+// next to it. The previous content of every replaced or removed file is
+// backed up, so that destinations can be restored to their previous state if
+// anything fails along the way. Backups are kept within the engine's file
+// system, since destinations may live on a different file system.
+type transaction struct {
+	backup     string
+	backups    afero.Fs
+	fileSystem afero.Fs
+
+	// undo are the functions reverting all changes applied so far, in the
+	// order the changes were applied.
+	undo []func() error
+}
+
+func (e *Engine) transaction(fs afero.Fs, backup string) *transaction {
+	return &transaction{
+		backup:     backup,
+		backups:    e.fileSystem,
+		fileSystem: fs,
+	}
+}
+
+// write replaces the content of the given file atomically. Files having the
+// given content and mode already are left untouched, so that their
+// modification times stay the same.
+func (t *transaction) write(p string, b []byte, m os.FileMode) error {
+	i, err := t.fileSystem.Stat(p)
+	if os.IsNotExist(err) {
+		i = nil
+	} else if err != nil {
+		return tracer.Mask(err)
+	}
+
+	if i != nil {
+		x, err := afero.ReadFile(t.fileSystem, p)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		if bytes.Equal(x, b) && i.Mode().Perm() == m {
+			return nil
+		}
+	}
+
+	err = t.mkdir(filepath.Dir(p))
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	if i != nil {
+		err := t.save(p)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	// The temporary file is created within the same directory, because
+	// renaming files is only atomic within the same file system.
+	tmp := filepath.Join(filepath.Dir(p), "."+filepath.Base(p)+".pag-"+strconv.Itoa(len(t.undo)))
+
+	err = afero.WriteFile(t.fileSystem, tmp, b, m)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	// WriteFile does not change the mode of existing files, which is why the
+	// mode is ensured explicitly.
+	err = t.fileSystem.Chmod(tmp, m)
+	if err != nil {
+		t.fileSystem.Remove(tmp)
+		return tracer.Mask(err)
+	}
+
+	err = t.fileSystem.Rename(tmp, p)
+	if err != nil {
+		t.fileSystem.Remove(tmp)
+		return tracer.Mask(err)
+	}
+
+	if i == nil {
+		t.undo = append(t.undo, func() error {
+			return t.fileSystem.Remove(p)
+		})
+	}
+
+	return nil
+}
+
+// remove removes the given file after backing it up.
+func (t *transaction) remove(p string) error {
+	err := t.save(p)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	err = t.fileSystem.Remove(p)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+// rmdir removes the given empty directory.
+func (t *transaction) rmdir(p string) error {
+	err := t.fileSystem.Remove(p)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	t.undo = append(t.undo, func() error {
+		return t.fileSystem.MkdirAll(p, os.ModePerm)
+	})
+
+	return nil
+}
+
+// mkdir creates the given directory and all of its missing parents.
+func (t *transaction) mkdir(p string) error {
+	var l []string
+	for d := filepath.Clean(p); ; d = filepath.Dir(d) {
+		_, err := t.fileSystem.Stat(d)
+		if err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return tracer.Mask(err)
+		}
+
+		l = append(l, d)
+
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	err := t.fileSystem.MkdirAll(p, os.ModePerm)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	// Created directories are registered parents first, so that they are
+	// removed deepest first.
+	for i := len(l) - 1; i >= 0; i-- {
+		d := l[i]
+		t.undo = append(t.undo, func() error {
+			return t.fileSystem.Remove(d)
+		})
+	}
+
+	return nil
+}
+
+// save backs up the given file and registers its restoration.
+func (t *transaction) save(p string) error {
+	i, err := t.fileSystem.Stat(p)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	b, err := afero.ReadFile(t.fileSystem, p)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	err = t.backups.MkdirAll(t.backup, os.ModePerm)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	bck := filepath.Join(t.backup, strconv.Itoa(len(t.undo)))
+
+	err = afero.WriteFile(t.backups, bck, b, 0600)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	m := i.Mode().Perm()
+
+	t.undo = append(t.undo, func() error {
+		b, err := afero.ReadFile(t.backups, bck)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		err = t.fileSystem.MkdirAll(filepath.Dir(p), os.ModePerm)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		err = afero.WriteFile(t.fileSystem, p, b, m)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		err = t.fileSystem.Chmod(p, m)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		return nil
+	})
+
+	return nil
+}
+
+// rollback reverts all changes applied so far, the latest change first. All
+// changes are attempted to be reverted, even if reverting some of them fails.
+func (t *transaction) rollback() error {
+	var fai error
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		err := t.undo[i]()
+		if err != nil && fai == nil {
+			fai = err
+		}
+	}
+
+	t.undo = nil
+
+	if fai != nil {
+		return tracer.Mask(fai)
+	}
+
+	return nil
+}
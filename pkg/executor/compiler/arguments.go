@@ -0,0 +1,174 @@
+package compiler
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// builtin are the code generators built into protoc, which are not available
+// as plugins and therefore always require protoc.
+var builtin = map[string]bool{
+	"cpp":    true,
+	"csharp": true,
+	"java":   true,
+	"kotlin": true,
+	"objc":   true,
+	"php":    true,
+	"pyi":    true,
+	"python": true,
+	"ruby":   true,
+	"rust":   true,
+	"upb":    true,
+}
+
+// invocation is the parsed command line of a single protoc command.
+type invocation struct {
+	// directives are the code generators to run, in the order of the command
+	// line.
+	directives []directive
+	// imports are the directories to look for schema files in, as given via
+	// --proto_path.
+	imports []string
+	// inputs are the schema files to generate code for, as given on the
+	// command line.
+	inputs []string
+	// plugins are the directories to look for plugins in before looking into
+	// the PATH.
+	plugins []string
+}
+
+// directive is a single code generator of a protoc command, e.g. --go_out.
+type directive struct {
+	// name is the name of the code generator, e.g. "go" for --go_out.
+	name string
+	// output is the directory generated files are written to.
+	output string
+	// parameter is the parameter passed to the plugin, combined from the
+	// options of --<name>_out and all --<name>_opt arguments.
+	parameter string
+	// plugin is the name of the plugin, e.g. "protoc-gen-go".
+	plugin string
+	// binary is the plugin binary to execute, which is the plugin name
+	// unless another binary is given via --plugin.
+	binary string
+}
+
+// parse parses the given protoc arguments. The returned bool is false if the
+// arguments use anything only protoc itself can handle, e.g. built-in code
+// generators or descriptor set output.
+func parse(args []string) (invocation, bool) {
+	var inv invocation
+
+	opt := map[string][]string{}
+	plg := map[string]string{}
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			inv.inputs = append(inv.inputs, a)
+			continue
+		}
+
+		k, v := a, ""
+		if i := strings.Index(a, "="); i != -1 {
+			k, v = a[:i], a[i+1:]
+		}
+
+		switch {
+		case k == "--descriptor_set_out" || k == "--dependency_out":
+			return invocation{}, false
+		case k == "--experimental_allow_proto3_optional":
+			// Proto3 optional fields are supported regardless.
+		case k == "--proto_path":
+			inv.imports = append(inv.imports, v)
+		case strings.HasPrefix(a, "-I") && len(a) > 2:
+			inv.imports = append(inv.imports, a[2:])
+		case k == "--plugin":
+			// Plugins are given either as "protoc-gen-NAME=PATH" or as a
+			// path to a binary named "protoc-gen-NAME".
+			if i := strings.Index(v, "="); i != -1 {
+				plg[v[:i]] = v[i+1:]
+			} else {
+				plg[filepath.Base(v)] = v
+			}
+		case strings.HasSuffix(k, "_opt"):
+			n := strings.TrimSuffix(strings.TrimPrefix(k, "--"), "_opt")
+			opt[n] = append(opt[n], v)
+		case strings.HasSuffix(k, "_out"):
+			d := directive{
+				name:   strings.TrimSuffix(strings.TrimPrefix(k, "--"), "_out"),
+				output: v,
+			}
+
+			// Plugin options are separated from the output directory with a
+			// colon, e.g. --go_out=paths=source_relative:pkg/.
+			if i := strings.Index(v, ":"); i != -1 {
+				d.parameter, d.output = v[:i], v[i+1:]
+			}
+
+			inv.directives = append(inv.directives, d)
+		default:
+			return invocation{}, false
+		}
+	}
+
+	for i, d := range inv.directives {
+		d.plugin = "protoc-gen-" + d.name
+		d.binary = d.plugin
+		if p, ok := plg[d.plugin]; ok {
+			d.binary = p
+		} else if builtin[d.name] {
+			return invocation{}, false
+		}
+
+		// Output archives, e.g. --java_out=gen.jar, are written by protoc
+		// only.
+		if strings.HasSuffix(d.output, ".jar") || strings.HasSuffix(d.output, ".zip") {
+			return invocation{}, false
+		}
+
+		if l := opt[d.name]; len(l) != 0 {
+			d.parameter = strings.Join(append(nonEmpty(d.parameter), l...), ",")
+		}
+
+		inv.directives[i] = d
+	}
+
+	if len(inv.imports) == 0 {
+		inv.imports = []string{"."}
+	}
+
+	return inv, true
+}
+
+// virtual returns the name of the given input within the proto paths, the
+// same way protoc does. Inputs existing on disk within any proto path are
+// made relative to that proto path, e.g. "pbf/user/api.proto" becomes
+// "user/api.proto" for --proto_path=pbf/. All other inputs are used as they
+// are.
+func virtual(imports []string, p string, exists func(string) bool) string {
+	if !exists(p) {
+		return p
+	}
+
+	c := filepath.Clean(p)
+	for _, i := range imports {
+		i = filepath.Clean(i)
+
+		if i == "." && !filepath.IsAbs(c) && c != ".." && !strings.HasPrefix(c, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(c)
+		}
+
+		if strings.HasPrefix(c, i+string(filepath.Separator)) {
+			return filepath.ToSlash(strings.TrimPrefix(c, i+string(filepath.Separator)))
+		}
+	}
+
+	return p
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return []string{s}
+}
@@ -0,0 +1,496 @@
+// Package compiler executes protoc commands without protoc. Schemas are
+// compiled using a pure Go compiler and protoc plugins, e.g. protoc-gen-go,
+// are invoked directly, so that neither protoc nor any specific version of it
+// has to be installed.
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
+	"github.com/bufbuild/protocompile/reporter"
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/xh3b4sd/pag/pkg/executor"
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+const (
+	// Binary is the binary of the commands executed without it.
+	Binary = "protoc"
+)
+
+type Config struct {
+	// Fallback executes all commands which cannot be executed without
+	// protoc, e.g. commands using the code generators built into protoc like
+	// --java_out, as well as commands of any other binary.
+	Fallback executor.Interface
+	// FileSystem is where schema files are read from and where generated
+	// files are written to.
+	FileSystem afero.Fs
+}
+
+// Compiler executes protoc commands by compiling their schema files into
+// descriptors and passing code generator requests to the respective protoc
+// plugins via stdin. The files of the code generator responses are written
+// into the output directories the same way protoc writes them.
+type Compiler struct {
+	fallback   executor.Interface
+	fileSystem afero.Fs
+}
+
+func New(config Config) (*Compiler, error) {
+	if config.Fallback == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Fallback must not be empty", config)
+	}
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
+
+	c := &Compiler{
+		fallback:   config.Fallback,
+		fileSystem: config.FileSystem,
+	}
+
+	return c, nil
+}
+
+// Execute executes the given command. The returned output resembles the
+// output of protoc, so that failures can be reported the same way.
+func (c *Compiler) Execute(ctx context.Context, cmd generate.Command) ([]byte, error) {
+	inv, ok := parse(cmd.Arguments)
+	inv.plugins = cmd.Plugins
+	if cmd.Binary != Binary || !ok {
+		out, err := c.fallback.Execute(ctx, cmd)
+		if err != nil {
+			return out, tracer.Mask(err)
+		}
+
+		return out, nil
+	}
+
+	var out bytes.Buffer
+
+	err := c.execute(ctx, inv, &out)
+	if err != nil {
+		return out.Bytes(), tracer.Mask(err)
+	}
+
+	return out.Bytes(), nil
+}
+
+func (c *Compiler) execute(ctx context.Context, inv invocation, out io.Writer) error {
+	if len(inv.directives) == 0 {
+		fmt.Fprintf(out, "Missing output directives.\n")
+		return tracer.Mask(executionFailedError)
+	}
+	if len(inv.inputs) == 0 {
+		fmt.Fprintf(out, "Missing input file.\n")
+		return tracer.Mask(executionFailedError)
+	}
+
+	var inp []string
+	for _, i := range inv.inputs {
+		inp = append(inp, virtual(inv.imports, i, c.exists))
+	}
+
+	files, err := c.compile(ctx, inv.imports, inp, out)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	// Protoc only writes generated files once all code generators succeeded,
+	// which is why all generated files are kept in memory until then.
+	var gen []*generated
+	for _, d := range inv.directives {
+		g, err := c.generate(ctx, d, files, inp, inv.plugins, out)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		gen = append(gen, g)
+	}
+
+	for i, g := range gen {
+		err := c.write(inv.directives[i], g, out)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// compile compiles the given schema files and all of their imports. Errors
+// and warnings are reported in the format of protoc, e.g.
+// "user/api.proto:5:1: syntax error: expecting ';'", and cause an executionFailedError.
+func (c *Compiler) compile(ctx context.Context, imports []string, inputs []string, out io.Writer) (linker.Files, error) {
+	rep := reporter.NewReporter(
+		func(err reporter.ErrorWithPos) error {
+			fmt.Fprintf(out, "%s\n", err.Error())
+			return nil
+		},
+		func(err reporter.ErrorWithPos) {
+			p := err.GetPosition()
+			fmt.Fprintf(out, "%s: warning: %s\n", p, err.Unwrap())
+		},
+	)
+
+	// Schema files are looked up in all proto paths in order, falling back to
+	// the standard imports like "google/protobuf/timestamp.proto".
+	res := protocompile.ResolverFunc(func(n string) (protocompile.SearchResult, error) {
+		for _, i := range imports {
+			f, err := c.fileSystem.Open(filepath.Join(i, n))
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return protocompile.SearchResult{}, tracer.Mask(err)
+			}
+
+			return protocompile.SearchResult{Source: f}, nil
+		}
+
+		return protocompile.SearchResult{}, notFoundError(n)
+	})
+
+	com := protocompile.Compiler{
+		Resolver:       protocompile.WithStandardImports(res),
+		Reporter:       rep,
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+
+	files, err := com.Compile(ctx, inputs...)
+	if ctx.Err() != nil {
+		return nil, tracer.Mask(ctx.Err())
+	} else if errors.Is(err, reporter.ErrInvalidSource) {
+		return nil, tracer.Mask(executionFailedError)
+	} else if err != nil {
+		fmt.Fprintf(out, "%s\n", err)
+		return nil, tracer.Mask(executionFailedError)
+	}
+
+	return files, nil
+}
+
+// notFoundError is the error of schema files which cannot be found in any
+// proto path, using the message protoc uses for them.
+type notFoundError string
+
+func (e notFoundError) Error() string {
+	return string(e) + ": File not found."
+}
+
+func (e notFoundError) Unwrap() error {
+	return os.ErrNotExist
+}
+
+// generated are the files of a single code generator, indexed by their names
+// relative to the output directory and ordered the way they got generated.
+type generated struct {
+	content map[string]string
+	names   []string
+}
+
+// generate passes the code generator request for the given schema files to
+// the plugin of the given directive and returns the generated files. The
+// plugin is looked up in the given plugin directories before the PATH.
+func (c *Compiler) generate(ctx context.Context, d directive, files linker.Files, inputs []string, plugins []string, out io.Writer) (*generated, error) {
+	req := request(files, inputs, d.parameter)
+
+	b, err := proto.Marshal(req)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	p, err := executor.LookPath(d.binary, plugins)
+	if err != nil {
+		fmt.Fprintf(out, "%s: program not found or is not executable\n", d.plugin)
+		fmt.Fprintf(out, "Please specify a program using absolute path or make sure the program is available in your PATH system variable\n")
+		fmt.Fprintf(out, "--%s_out: %s: Plugin failed with status code 1.\n", d.name, d.plugin)
+		return nil, tracer.Mask(executionFailedError)
+	}
+
+	var stdout bytes.Buffer
+	{
+		x := exec.CommandContext(ctx, p)
+		x.Stdin = bytes.NewReader(b)
+		x.Stdout = &stdout
+		x.Stderr = out
+
+		err := x.Run()
+		if ctx.Err() != nil {
+			return nil, tracer.Mask(ctx.Err())
+		}
+
+		var exi *exec.ExitError
+		if errors.As(err, &exi) {
+			fmt.Fprintf(out, "--%s_out: %s: Plugin failed with status code %d.\n", d.name, d.plugin, exi.ExitCode())
+			return nil, tracer.Mask(executionFailedError)
+		} else if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	var res pluginpb.CodeGeneratorResponse
+	{
+		err := proto.Unmarshal(stdout.Bytes(), &res)
+		if err != nil {
+			fmt.Fprintf(out, "--%s_out: %s: Plugin output is unparseable: %s\n", d.name, d.plugin, err)
+			return nil, tracer.Mask(executionFailedError)
+		}
+	}
+
+	if res.Error != nil {
+		fmt.Fprintf(out, "--%s_out: %s\n", d.name, res.GetError())
+		return nil, tracer.Mask(executionFailedError)
+	}
+
+	if res.GetSupportedFeatures()&uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) == 0 {
+		for _, f := range inputs {
+			if optional(files.FindFileByPath(f)) {
+				fmt.Fprintf(out, "%s is a proto3 file that contains optional fields, but code generator %s hasn't been updated to support optional fields in proto3. Please ask the owner of this code generator to support proto3 optional.\n", f, d.plugin)
+				return nil, tracer.Mask(executionFailedError)
+			}
+		}
+	}
+
+	g := &generated{content: map[string]string{}}
+
+	var prev string
+	for _, f := range res.File {
+		n := f.GetName()
+		if n == "" {
+			n = prev
+		}
+		prev = n
+
+		if !local(n) {
+			fmt.Fprintf(out, "--%s_out: Invalid file name: %s\n", d.name, n)
+			return nil, tracer.Mask(executionFailedError)
+		}
+
+		x, ok := g.content[n]
+
+		// Files without insertion point are created, or extended if the
+		// previous entry of the response had the same name.
+		if f.GetInsertionPoint() == "" {
+			if !ok {
+				g.names = append(g.names, n)
+			}
+
+			g.content[n] = x + f.GetContent()
+			continue
+		}
+
+		if !ok {
+			fmt.Fprintf(out, "--%s_out: %s: Tried to insert into file that doesn't exist.\n", d.name, n)
+			return nil, tracer.Mask(executionFailedError)
+		}
+
+		y, ok := insert(x, f.GetInsertionPoint(), f.GetContent())
+		if !ok {
+			fmt.Fprintf(out, "--%s_out: %s: insertion point \"%s\" not found.\n", d.name, n, f.GetInsertionPoint())
+			return nil, tracer.Mask(executionFailedError)
+		}
+
+		g.content[n] = y
+	}
+
+	return g, nil
+}
+
+// write writes the given generated files into the output directory of the
+// given directive. The output directory must exist, the same way protoc
+// expects it to exist.
+func (c *Compiler) write(d directive, g *generated, out io.Writer) error {
+	ok, err := afero.DirExists(c.fileSystem, d.output)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	if !ok {
+		fmt.Fprintf(out, "%s: No such file or directory\n", d.output)
+		return tracer.Mask(executionFailedError)
+	}
+
+	for _, n := range g.names {
+		p := filepath.Join(d.output, filepath.FromSlash(n))
+
+		err := c.fileSystem.MkdirAll(filepath.Dir(p), os.ModePerm)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		err = afero.WriteFile(c.fileSystem, p, []byte(g.content[n]), 0644)
+		if err != nil {
+			return tracer.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Compiler) exists(p string) bool {
+	ok, err := afero.Exists(c.fileSystem, p)
+	if err != nil {
+		return false
+	}
+
+	return ok
+}
+
+// request returns the code generator request for the given schema files.
+// The request contains the descriptors of the given schema files and of all
+// of their transitive imports, where every file is listed after all of its
+// imports.
+func request(files linker.Files, inputs []string, parameter string) *pluginpb.CodeGeneratorRequest {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: inputs,
+	}
+
+	if parameter != "" {
+		req.Parameter = proto.String(parameter)
+	}
+
+	var l []protoreflect.FileDescriptor
+	{
+		see := map[string]bool{}
+
+		var add func(f protoreflect.FileDescriptor)
+		add = func(f protoreflect.FileDescriptor) {
+			if see[f.Path()] {
+				return
+			}
+			see[f.Path()] = true
+
+			imp := f.Imports()
+			for i := 0; i < imp.Len(); i++ {
+				add(imp.Get(i).FileDescriptor)
+			}
+
+			l = append(l, f)
+		}
+
+		for _, f := range files {
+			add(f)
+		}
+	}
+
+	gen := map[string]bool{}
+	for _, i := range inputs {
+		gen[i] = true
+	}
+
+	for _, f := range l {
+		d := protodesc.ToFileDescriptorProto(f)
+
+		req.ProtoFile = append(req.ProtoFile, d)
+
+		if gen[f.Path()] {
+			req.SourceFileDescriptors = append(req.SourceFileDescriptors, d)
+		}
+	}
+
+	return req
+}
+
+// optional returns whether the given schema file contains any proto3
+// optional field.
+func optional(f linker.File) bool {
+	if f == nil {
+		return false
+	}
+
+	d := protodesc.ToFileDescriptorProto(f)
+	if d.GetSyntax() != "proto3" {
+		return false
+	}
+
+	var msg func(l []*descriptorpb.DescriptorProto) bool
+	msg = func(l []*descriptorpb.DescriptorProto) bool {
+		for _, m := range l {
+			for _, x := range m.Field {
+				if x.GetProto3Optional() {
+					return true
+				}
+			}
+
+			if msg(m.NestedType) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return msg(d.MessageType)
+}
+
+// insert inserts the given content into the given file right before the line
+// of the given insertion point, e.g. "// @@protoc_insertion_point(imports)".
+// Every inserted line is indented the same way the insertion point is.
+func insert(file string, point string, content string) (string, bool) {
+	m := "@@protoc_insertion_point(" + point + ")"
+
+	i := strings.Index(file, m)
+	if i == -1 {
+		return "", false
+	}
+
+	s := strings.LastIndex(file[:i], "\n") + 1
+
+	var ind string
+	for _, r := range file[s:i] {
+		if r != ' ' && r != '\t' {
+			break
+		}
+
+		ind += string(r)
+	}
+
+	var b strings.Builder
+	for _, l := range strings.SplitAfter(content, "\n") {
+		if l == "" {
+			continue
+		}
+
+		if l != "\n" {
+			b.WriteString(ind)
+		}
+
+		b.WriteString(l)
+	}
+
+	return file[:s] + b.String() + file[s:], true
+}
+
+// local returns whether the given file name of a code generator response is
+// a relative path pointing into the output directory.
+func local(n string) bool {
+	if n == "" || strings.HasPrefix(n, "/") {
+		return false
+	}
+
+	for _, s := range strings.Split(n, "/") {
+		if s == ".." {
+			return false
+		}
+	}
+
+	return true
+}
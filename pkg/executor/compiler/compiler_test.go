@@ -0,0 +1,369 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/xh3b4sd/pag/pkg/executor/fake"
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// plugin is the environment variable causing the test binary to act as protoc
+// plugin, so that the tests do not depend on any installed plugin.
+const plugin = "PAG_COMPILER_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(plugin) != "" {
+		os.Exit(testPlugin())
+	}
+
+	err := os.Setenv(plugin, "1")
+	if err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// Test_Compiler_Execute tests the execution of protoc commands without
+// protoc. The tests here run the test binary itself as protoc plugin and
+// ensure that schemas are compiled, that plugins get the expected requests
+// and that their responses are written the same way protoc writes them. The
+// golden files contain the output of every command followed by all generated
+// files.
+//
+//	go test ./pkg/executor/compiler -run Test_Compiler_Execute -update
+func Test_Compiler_Execute(t *testing.T) {
+	testCases := []struct {
+		arg []string
+		src map[string]string
+		err bool
+	}{
+		// Case 0 ensures that schema files given as paths within their proto
+		// path are compiled together with local and standard imports.
+		{
+			arg: []string{"--experimental_allow_proto3_optional", "--test_out=gen/", "--proto_path=pbf/", "pbf/user/api.proto"},
+			src: map[string]string{
+				"pbf/user/api.proto":    "syntax = \"proto3\";\n\npackage user;\n\nimport \"shared/type.proto\";\nimport \"google/protobuf/timestamp.proto\";\n\nmessage SearchI {\n  optional shared.Type type = 1;\n  google.protobuf.Timestamp time = 2;\n}\n",
+				"pbf/shared/type.proto": "syntax = \"proto3\";\n\npackage shared;\n\nmessage Type {}\n",
+			},
+		},
+		// Case 1 ensures that plugin options of output directives and option
+		// arguments are combined into the plugin parameter.
+		{
+			arg: []string{"--test_out=a=1:gen/", "--test_opt=b=2", "--test_opt=c=3", "--proto_path=.", "user/api.proto"},
+			src: map[string]string{
+				"user/api.proto": "syntax = \"proto3\";\n\nmessage SearchI {}\n",
+			},
+		},
+		// Case 2 ensures that content is inserted at insertion points of
+		// files generated before.
+		{
+			arg: []string{"--test_out=insert:gen/", "--proto_path=.", "user/api.proto"},
+			src: map[string]string{
+				"user/api.proto": "syntax = \"proto3\";\n\nmessage SearchI {}\n",
+			},
+		},
+		// Case 3 ensures that syntax errors are reported the same way protoc
+		// reports them, without writing any file.
+		{
+			arg: []string{"--test_out=gen/", "--proto_path=.", "user/api.proto"},
+			src: map[string]string{
+				"user/api.proto": "syntax = \"proto3\";\n\nmessage SearchI {\n  string name = 1\n}\n",
+			},
+			err: true,
+		},
+		// Case 4 ensures that errors of code generator responses are
+		// reported.
+		{
+			arg: []string{"--test_out=error:gen/", "--proto_path=.", "user/api.proto"},
+			src: map[string]string{
+				"user/api.proto": "syntax = \"proto3\";\n\nmessage SearchI {}\n",
+			},
+			err: true,
+		},
+		// Case 5 ensures that failing plugins are reported together with
+		// their output.
+		{
+			arg: []string{"--test_out=exit:gen/", "--proto_path=.", "user/api.proto"},
+			src: map[string]string{
+				"user/api.proto": "syntax = \"proto3\";\n\nmessage SearchI {}\n",
+			},
+			err: true,
+		},
+		// Case 6 ensures that missing plugins are reported.
+		{
+			arg: []string{"--missing_out=gen/", "--proto_path=.", "user/api.proto"},
+			src: map[string]string{
+				"user/api.proto": "syntax = \"proto3\";\n\nmessage SearchI {}\n",
+			},
+			err: true,
+		},
+		// Case 7 ensures that missing imports are reported.
+		{
+			arg: []string{"--test_out=gen/", "--proto_path=.", "user/api.proto"},
+			src: map[string]string{
+				"user/api.proto": "syntax = \"proto3\";\n\nimport \"shared/type.proto\";\n",
+			},
+			err: true,
+		},
+		// Case 8 ensures that missing schema files are reported.
+		{
+			arg: []string{"--test_out=gen/", "--proto_path=.", "user/api.proto"},
+			err: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for p, s := range tc.src {
+				mustWriteFile(fs, p, s)
+			}
+
+			err := fs.MkdirAll("gen", os.ModePerm)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := mustNew(fake.New(), fs)
+
+			var b bytes.Buffer
+
+			out, err := c.Execute(context.Background(), mustCommand(tc.arg))
+			if tc.err && !IsExecutionFailed(err) {
+				t.Fatalf("expected executionFailedError got %#v", err)
+			} else if !tc.err && err != nil {
+				t.Fatalf("expected no error got %#v\n%s", err, out)
+			}
+
+			b.Write(out)
+			b.WriteString("\n")
+
+			err = afero.Walk(fs, "gen", func(p string, i os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+
+				if i.IsDir() {
+					return nil
+				}
+
+				c, err := afero.ReadFile(fs, p)
+				if err != nil {
+					return err
+				}
+
+				b.WriteString("==> " + p + "\n")
+				b.Write(c)
+
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := b.Bytes()
+
+			p := filepath.Join("testdata/execute", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Compiler_Execute_Fallback tests that commands requiring protoc are
+// executed by the fallback executor.
+func Test_Compiler_Execute_Fallback(t *testing.T) {
+	testCases := []struct {
+		cmd generate.Command
+		fal bool
+	}{
+		// Case 0 ensures that code generators built into protoc are executed
+		// by protoc.
+		{
+			cmd: generate.Command{Binary: "protoc", Arguments: []string{"--java_out=gen", "--proto_path=.", "user/api.proto"}},
+			fal: true,
+		},
+		// Case 1 ensures that built-in code generators replaced by plugins
+		// are not executed by protoc.
+		{
+			cmd: generate.Command{Binary: "protoc", Arguments: []string{"--java_out=gen", "--plugin=protoc-gen-java=" + os.Args[0], "--proto_path=.", "user/api.proto"}},
+			fal: false,
+		},
+		// Case 2 ensures that unsupported arguments are handled by protoc.
+		{
+			cmd: generate.Command{Binary: "protoc", Arguments: []string{"--test_out=gen", "--descriptor_set_out=api.pb", "--proto_path=.", "user/api.proto"}},
+			fal: true,
+		},
+		// Case 3 ensures that other binaries than protoc are executed by the
+		// fallback executor.
+		{
+			cmd: generate.Command{Binary: "buf", Arguments: []string{"generate"}},
+			fal: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+
+			mustWriteFile(fs, "user/api.proto", "syntax = \"proto3\";\n\nmessage SearchI {}\n")
+
+			err := fs.MkdirAll("gen", os.ModePerm)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := fake.New()
+			c := mustNew(f, fs)
+
+			out, err := c.Execute(context.Background(), tc.cmd)
+			if err != nil {
+				t.Fatalf("expected no error got %#v\n%s", err, out)
+			}
+
+			if tc.fal != (len(f.Commands()) == 1) {
+				t.Fatalf("expected fallback %t got commands %#v", tc.fal, f.Commands())
+			}
+		})
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
+
+// mustCommand returns a protoc command for the given arguments, which runs
+// the test binary as plugin for the "test" code generator.
+func mustCommand(args []string) generate.Command {
+	return generate.Command{
+		Binary:    "protoc",
+		Arguments: append([]string{"--plugin=protoc-gen-test=" + os.Args[0]}, args...),
+	}
+}
+
+func mustNew(f *fake.Executor, fs afero.Fs) *Compiler {
+	c, err := New(Config{Fallback: f, FileSystem: fs})
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+func mustWriteFile(fs afero.Fs, p string, s string) {
+	err := afero.WriteFile(fs, p, []byte(s), 0600)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// testPlugin acts as protoc plugin. For every schema file to generate, it
+// generates a file listing the plugin parameter, all schema files of the
+// request and all messages of the schema file. The parameters "error",
+// "exit" and "insert" cause responses with errors, failures and insertions.
+func testPlugin() int {
+	b, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		panic(err)
+	}
+
+	var req pluginpb.CodeGeneratorRequest
+	err = proto.Unmarshal(b, &req)
+	if err != nil {
+		panic(err)
+	}
+
+	if req.GetParameter() == "exit" {
+		fmt.Fprintf(os.Stderr, "something went wrong\n")
+		return 3
+	}
+
+	res := &pluginpb.CodeGeneratorResponse{
+		SupportedFeatures: proto.Uint64(uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)),
+	}
+
+	if req.GetParameter() == "error" {
+		res.Error = proto.String("invalid parameter error")
+	}
+
+	for _, n := range req.FileToGenerate {
+		var s strings.Builder
+
+		s.WriteString("parameter: " + req.GetParameter() + "\n")
+
+		for _, f := range req.ProtoFile {
+			s.WriteString("file: " + f.GetName() + "\n")
+
+			if f.GetName() != n {
+				continue
+			}
+
+			for _, m := range f.MessageType {
+				s.WriteString("message: " + m.GetName() + "\n")
+
+				for _, x := range m.Field {
+					s.WriteString(fmt.Sprintf("field: %s %s optional=%t\n", x.GetName(), x.GetTypeName(), x.GetProto3Optional()))
+				}
+			}
+		}
+
+		s.WriteString("  // @@protoc_insertion_point(end)\n")
+
+		f := strings.TrimSuffix(n, ".proto") + ".txt"
+
+		res.File = append(res.File, &pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(f),
+			Content: proto.String(s.String()),
+		})
+
+		if req.GetParameter() == "insert" {
+			res.File = append(res.File, &pluginpb.CodeGeneratorResponse_File{
+				Name:           proto.String(f),
+				InsertionPoint: proto.String("end"),
+				Content:        proto.String("inserted\n\nlines\n"),
+			})
+		}
+	}
+
+	b, err = proto.Marshal(res)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = os.Stdout.Write(b)
+	if err != nil {
+		panic(err)
+	}
+
+	return 0
+}
@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"context"
+	"os/exec"
+
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+type Config struct{}
+
+// Executor executes commands as processes of the operating system. Processes
+// are killed once the context given to Execute is cancelled. Binaries and the
+// plugins they invoke are looked up in the plugin directories of the command
+// before looking into the PATH.
+type Executor struct{}
+
+func New(config Config) (*Executor, error) {
+	e := &Executor{}
+
+	return e, nil
+}
+
+func (e *Executor) Execute(ctx context.Context, c generate.Command) ([]byte, error) {
+	// Binaries which cannot be found are executed as they are, so that the
+	// failure is reported the same way for all of them.
+	b, err := LookPath(c.Binary, c.Plugins)
+	if err != nil {
+		b = c.Binary
+	}
+
+	x := exec.CommandContext(ctx, b, c.Arguments...)
+	x.Env = env(c.Plugins)
+
+	out, err := x.CombinedOutput()
+	if err != nil {
+		return out, tracer.Mask(err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,183 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+// Executor records all executed commands instead of executing them. If it is
+// created with a file system, it additionally acts as stand-in for protoc, so
+// that the complete generation flow can be tested without protoc and its
+// plugins being installed.
+type Executor struct {
+	commands   []generate.Command
+	fileSystem afero.Fs
+	mutex      sync.Mutex
+}
+
+// New returns an executor only recording the executed commands.
+func New() *Executor {
+	return &Executor{}
+}
+
+// NewProtoc returns an executor recording the executed commands and emulating
+// protoc on the given file system. For every --<plugin>_out argument and every
+// schema file a stub file is written into the respective output directory,
+// e.g. "pkg/pbf/user/api.go.pb" for --go_out=pkg/pbf/user/ and
+// pbf/user/api.proto.
+func NewProtoc(fs afero.Fs) *Executor {
+	return &Executor{fileSystem: fs}
+}
+
+// Commands returns all recorded commands sorted by their string
+// representation, since commands may be executed concurrently.
+func (e *Executor) Commands() []generate.Command {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	l := append([]generate.Command{}, e.commands...)
+
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].String() < l[j].String()
+	})
+
+	return l
+}
+
+func (e *Executor) Execute(ctx context.Context, c generate.Command) ([]byte, error) {
+	e.mutex.Lock()
+	e.commands = append(e.commands, c)
+	e.mutex.Unlock()
+
+	if ctx.Err() != nil {
+		return nil, tracer.Mask(ctx.Err())
+	}
+
+	if e.fileSystem == nil || c.Binary != "protoc" {
+		return nil, nil
+	}
+
+	out, err := e.protoc(c.Arguments)
+	if err != nil {
+		return out, tracer.Mask(err)
+	}
+
+	return out, nil
+}
+
+func (e *Executor) protoc(args []string) ([]byte, error) {
+	var inc []string
+	var out map[string]string
+	var src []string
+	{
+		out = map[string]string{}
+
+		for _, a := range args {
+			if !strings.HasPrefix(a, "--") {
+				src = append(src, a)
+				continue
+			}
+
+			k, v := a, ""
+			if i := strings.Index(a, "="); i != -1 {
+				k, v = a[:i], a[i+1:]
+			}
+
+			if k == "--proto_path" {
+				inc = append(inc, v)
+				continue
+			}
+
+			if strings.HasSuffix(k, "_out") {
+				// Plugin options are separated from the output directory with
+				// a colon, e.g. --go_out=paths=source_relative:pkg/.
+				if i := strings.LastIndex(v, ":"); i != -1 {
+					v = v[i+1:]
+				}
+
+				out[strings.TrimSuffix(strings.TrimPrefix(k, "--"), "_out")] = v
+			}
+		}
+	}
+
+	if len(out) == 0 {
+		return []byte("Missing output directives.\n"), tracer.Mask(executionFailedError)
+	}
+	if len(src) == 0 {
+		return []byte("Missing input file.\n"), tracer.Mask(executionFailedError)
+	}
+
+	for _, s := range src {
+		ok, err := e.exists(inc, s)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		if !ok {
+			return []byte(fmt.Sprintf("%s: File not found.\n", s)), tracer.Mask(executionFailedError)
+		}
+	}
+
+	var plg []string
+	for k := range out {
+		plg = append(plg, k)
+	}
+
+	sort.Strings(plg)
+
+	for _, p := range plg {
+		for _, s := range src {
+			f := filepath.Join(out[p], strings.TrimSuffix(filepath.Base(s), ".proto")+"."+p+".pb")
+			b := fmt.Sprintf("// Code generated by protoc-gen-%s. DO NOT EDIT.\n// source: %s\n", p, s)
+
+			err := e.fileSystem.MkdirAll(filepath.Dir(f), os.ModePerm)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			err = afero.WriteFile(e.fileSystem, f, []byte(b), 0600)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// exists returns whether the given schema file can be found relative to the
+// working directory or any of the given proto paths. Absolute schema files are
+// looked up as they are.
+func (e *Executor) exists(inc []string, s string) (bool, error) {
+	if filepath.IsAbs(s) {
+		ok, err := afero.Exists(e.fileSystem, s)
+		if err != nil {
+			return false, tracer.Mask(err)
+		}
+
+		return ok, nil
+	}
+
+	for _, p := range append([]string{"."}, inc...) {
+		ok, err := afero.Exists(e.fileSystem, filepath.Join(p, s))
+		if err != nil {
+			return false, tracer.Mask(err)
+		}
+
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
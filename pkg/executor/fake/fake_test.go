@@ -0,0 +1,90 @@
+package fake
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+// Test_Fake_Protoc tests the emulation of protoc. The tests here ensure that
+// stub files are written for every plugin and schema file and that missing
+// schema files are reported the way protoc reports them.
+func Test_Fake_Protoc(t *testing.T) {
+	testCases := []struct {
+		arg []string
+		fil []string
+		out string
+	}{
+		// Case 0 ensures that stub files are written for every plugin and
+		// every schema file.
+		{
+			arg: []string{"--go_out=pkg/", "--go-grpc_out=paths=source_relative:pkg/", "--proto_path=.", "pbf/user/api.proto", "pbf/user/create.proto"},
+			fil: []string{
+				"pkg/api.go-grpc.pb",
+				"pkg/api.go.pb",
+				"pkg/create.go-grpc.pb",
+				"pkg/create.go.pb",
+			},
+		},
+		// Case 1 ensures that schema files are looked up within the proto
+		// paths.
+		{
+			arg: []string{"--js_out=src/", "--proto_path=pbf", "user/api.proto"},
+			fil: []string{
+				"src/api.js.pb",
+			},
+		},
+		// Case 2 ensures that missing schema files cause an error.
+		{
+			arg: []string{"--go_out=pkg/", "--proto_path=.", "pbf/user/delete.proto"},
+			out: "pbf/user/delete.proto: File not found.\n",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+
+			for _, p := range []string{"pbf/user/api.proto", "pbf/user/create.proto"} {
+				err := afero.WriteFile(fs, p, nil, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			e := NewProtoc(fs)
+
+			out, err := e.Execute(context.Background(), generate.Command{Binary: "protoc", Arguments: tc.arg})
+			if tc.out == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tc.out != "" && !IsExecutionFailed(err) {
+				t.Fatalf("expected executionFailedError got %#v", err)
+			}
+
+			if string(out) != tc.out {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.out, string(out)))
+			}
+
+			for _, f := range tc.fil {
+				ok, err := afero.Exists(fs, f)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !ok {
+					t.Fatalf("expected %s to exist", f)
+				}
+			}
+
+			if len(e.Commands()) != 1 {
+				t.Fatalf("expected 1 recorded command got %d", len(e.Commands()))
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// LookPath searches for the given binary in the given directories first and
+// in the directories of the PATH afterwards. Binaries given as path, e.g.
+// "./bin/protoc-gen-go", are not searched for.
+func LookPath(file string, dirs []string) (string, error) {
+	if strings.ContainsRune(file, filepath.Separator) {
+		return exec.LookPath(file)
+	}
+
+	for _, d := range abs(dirs) {
+		p := filepath.Join(d, file)
+
+		i, err := os.Stat(p)
+		if err == nil && !i.IsDir() && i.Mode()&0111 != 0 {
+			return p, nil
+		}
+	}
+
+	return exec.LookPath(file)
+}
+
+// abs returns the absolute paths of the given directories, so that they do
+// not depend on the working directory of executed commands.
+func abs(dirs []string) []string {
+	var l []string
+	for _, d := range dirs {
+		a, err := filepath.Abs(d)
+		if err != nil {
+			continue
+		}
+
+		l = append(l, a)
+	}
+
+	return l
+}
+
+// env returns the environment of commands executed with the given plugin
+// directories, which are prepended to the PATH so that plugins invoked by the
+// command take precedence over globally installed ones.
+func env(dirs []string) []string {
+	l := abs(dirs)
+	if len(l) == 0 {
+		return nil
+	}
+
+	p := strings.Join(append(l, os.Getenv("PATH")), string(os.PathListSeparator))
+
+	return append(os.Environ(), "PATH="+p)
+}
@@ -0,0 +1,15 @@
+package executor
+
+import (
+	"context"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+// Interface executes the commands of generators, e.g. protoc invocations.
+// Implementations other than the default os/exec based executor allow to
+// record commands in tests or to run them elsewhere entirely.
+type Interface interface {
+	// Execute executes the given command and returns its combined output.
+	Execute(ctx context.Context, c generate.Command) ([]byte, error)
+}
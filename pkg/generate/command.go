@@ -17,6 +17,10 @@ type Command struct {
 	// the directory structure has to be ensured so that the gRPC tooling can
 	// work properly since it is extremely picky with folders not existing.
 	Directory string
+	// Plugins are the directories to look for protoc plugins in before
+	// looking into the PATH, e.g. "./bin". Executors make them available to
+	// the executed binary.
+	Plugins []string
 }
 
 // String joins the binary and its arguments resulting in one concatenated
@@ -24,17 +28,16 @@ type Command struct {
 // specific example below and how the ".proto" files are provided as separate
 // positional arguments.
 //
-//     protoc \
+//	protoc \
 //
-//         --go-grpc_out=./pkg/pbf/user/ \
-//         --proto_path=./pbf/user/ \
-//
-//         pbf/user/api.proto \
-//         pbf/user/create.proto \
-//         pbf/user/delete.proto \
-//         pbf/user/search.proto \
-//         pbf/user/update.proto
+//	    --go-grpc_out=./pkg/pbf/user/ \
+//	    --proto_path=./pbf/user/ \
 //
+//	    pbf/user/api.proto \
+//	    pbf/user/create.proto \
+//	    pbf/user/delete.proto \
+//	    pbf/user/search.proto \
+//	    pbf/user/update.proto
 func (c Command) String() string {
 	return fmt.Sprintf("%s %s", c.Binary, strings.Join(c.Arguments, " "))
 }
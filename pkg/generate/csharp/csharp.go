@@ -0,0 +1,353 @@
+package csharp
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+	"github.com/xh3b4sd/pag/pkg/schema"
+)
+
+const (
+	Binary = "protoc"
+	// MsgArg is the specific argument string required in order to generate
+	// csharp classes based on gRPC messages.
+	MsgArg = "--experimental_allow_proto3_optional --csharp_out=%s --proto_path=%s %s"
+	// SvcArg is the specific argument string required in order to generate
+	// csharp clients and service base classes based on gRPC services. Note
+	// that the respective protoc plugin is shipped with the Grpc.Tools
+	// package and has to be available as grpc_csharp_plugin.
+	SvcArg = "--experimental_allow_proto3_optional --grpc_out=%s --plugin=protoc-gen-grpc=grpc_csharp_plugin --proto_path=%s %s"
+)
+
+const (
+	// GrpcVersion is the default version of the Grpc.Core.Api package the
+	// generated .csproj file references.
+	GrpcVersion = "2.62.0"
+	// ProtobufVersion is the default version of the Google.Protobuf package
+	// the generated .csproj file references.
+	ProtobufVersion = "3.25.3"
+)
+
+type Config struct {
+	FileSystem afero.Fs
+	Schema     *schema.Schema
+
+	Destination string
+	Exclude     []string
+	// Grpc is the exact version of the Grpc.Core.Api package the generated
+	// .csproj file references. Defaults to GrpcVersion.
+	Grpc string
+	// Project is the name of the generated .csproj file and the root
+	// namespace of the generated resource classes, e.g. "Api".
+	Project string
+	// Protobuf is the exact version of the Google.Protobuf package the
+	// generated .csproj file references. Defaults to ProtobufVersion.
+	Protobuf string
+	Source   string
+}
+
+type CSharp struct {
+	schema *schema.Schema
+
+	destination string
+	grpc        string
+	project     string
+	protobuf    string
+	source      string
+}
+
+func New(config Config) (*CSharp, error) {
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
+
+	if config.Destination == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Destination must not be empty", config)
+	}
+	if config.Project == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Project must not be empty", config)
+	}
+	if config.Source == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Source must not be empty", config)
+	}
+
+	if config.Grpc == "" {
+		config.Grpc = GrpcVersion
+	}
+	if config.Protobuf == "" {
+		config.Protobuf = ProtobufVersion
+	}
+
+	var err error
+
+	s := config.Schema
+	if s == nil {
+		c := schema.Config{
+			FileSystem: config.FileSystem,
+
+			Exclude: config.Exclude,
+			Source:  config.Source,
+		}
+
+		s, err = schema.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	c := &CSharp{
+		schema: s,
+
+		destination: config.Destination,
+		grpc:        config.Grpc,
+		project:     config.Project,
+		protobuf:    config.Protobuf,
+		source:      config.Source,
+	}
+
+	return c, nil
+}
+
+// Commands returns the protoc commands generating csharp code. Every resource
+// directory is generated into its own directory within the destination, since
+// the csharp plugins name the generated files only after the proto files,
+// e.g. "Create.cs" for "create.proto", which would otherwise collide.
+func (c *CSharp) Commands() ([]generate.Command, error) {
+	dirs, err := c.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var cmds []generate.Command
+	for _, d := range dirs {
+		p, err := c.path(d)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		x := func(f string) generate.Command {
+			return generate.Command{
+				Binary:    Binary,
+				Arguments: strings.Split(fmt.Sprintf(f, p, c.source, strings.Join(d.Paths(), " ")), " "),
+				Directory: p,
+			}
+		}
+
+		cmds = append(cmds, x(MsgArg))
+		cmds = append(cmds, x(SvcArg))
+	}
+
+	return cmds, nil
+}
+
+// Files returns a minimal .csproj file in the destination, so that the
+// generated code can be referenced directly by .NET services, and one static
+// class per resource. The static classes live in the project's root namespace
+// and aggregate the resource's namespace the same way the typescript index.ts
+// file does. New input and output messages of create.proto can be created via
+// Api.User.Create.I() and Api.User.Create.O(), while the client of the
+// resource's service can be created via Api.User.Client(channel).
+func (c *CSharp) Files() ([]generate.File, error) {
+	dirs, err := c.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var l []generate.File
+
+	{
+		f := generate.File{
+			Path: filepath.Join(c.destination, c.project+".csproj"),
+		}
+
+		p := map[string]string{
+			"Grpc":     c.grpc,
+			"Project":  c.project,
+			"Protobuf": c.protobuf,
+		}
+
+		f.Bytes, err = c.render(f.Path, projectTemplate, p)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		l = append(l, f)
+	}
+
+	type Member struct {
+		Key  string
+		Name string
+	}
+
+	type Group struct {
+		Key     string
+		Members []Member
+	}
+
+	type Resource struct {
+		Name        string
+		Namespace   string
+		Clients     []Member
+		Descriptors []string
+		Groups      []Group
+		Members     []Member
+	}
+
+	for _, d := range dirs {
+		r := Resource{
+			Name:      toPascal(d.Resource()),
+			Namespace: c.project,
+		}
+
+		for _, f := range d.Files {
+			ns := toNamespace(f)
+
+			r.Descriptors = append(r.Descriptors, ns+toPascal(f.Base())+"Reflection")
+
+			for _, s := range f.Services {
+				m := Member{
+					Key:  "Client",
+					Name: ns + s.Name + "." + s.Name + "Client",
+				}
+
+				if len(d.Services()) > 1 {
+					m.Key = s.Name + "Client"
+				}
+
+				r.Clients = append(r.Clients, m)
+			}
+
+			if len(f.Messages) == 0 {
+				continue
+			}
+
+			g := Group{
+				Key: toPascal(f.Base()),
+			}
+
+			for _, x := range f.Messages {
+				m := Member{
+					Key:  x.Name,
+					Name: ns + x.Name,
+				}
+
+				if strings.HasPrefix(x.Name, g.Key) && len(x.Name) > len(g.Key) {
+					m.Key = strings.TrimPrefix(x.Name, g.Key)
+				}
+
+				// Members must not be named like their enclosing class in
+				// csharp.
+				if m.Key == g.Key {
+					continue
+				}
+
+				g.Members = append(g.Members, m)
+			}
+
+			// Classes must not be named like their enclosing class either,
+			// which is why the members of files named like their resource
+			// are defined on the resource class itself.
+			if g.Key == r.Name {
+				r.Members = append(r.Members, g.Members...)
+				continue
+			}
+
+			r.Groups = append(r.Groups, g)
+		}
+
+		rel, err := filepath.Rel(c.source, d.Path)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		f := generate.File{
+			Path: filepath.Join(c.destination, rel, r.Name+".cs"),
+		}
+
+		f.Bytes, err = c.render(f.Path, resourceTemplate, r)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		l = append(l, f)
+	}
+
+	return l, nil
+}
+
+// path returns the directory within the destination the given resource
+// directory is generated into.
+func (c *CSharp) path(d schema.Directory) (string, error) {
+	rel, err := filepath.Rel(c.source, d.Path)
+	if err != nil {
+		return "", tracer.Mask(err)
+	}
+
+	return filepath.Join(c.destination, rel), nil
+}
+
+func (c *CSharp) render(path string, tmpl string, data interface{}) ([]byte, error) {
+	s, err := template.New(path).Parse(tmpl)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var b bytes.Buffer
+	err = s.ExecuteTemplate(&b, path, data)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// toNamespace returns the fully qualified namespace prefix of the types
+// generated from the given file, e.g. "global::Pbf.User." for the package
+// "pbf.user". The namespace is either defined by the csharp_namespace option
+// or derived from the protocol buffer package.
+func toNamespace(f schema.File) string {
+	n, ok := f.Option("csharp_namespace")
+	if !ok {
+		var l []string
+		for _, x := range strings.Split(f.Package, ".") {
+			if x == "" {
+				continue
+			}
+
+			l = append(l, toPascal(x))
+		}
+
+		n = strings.Join(l, ".")
+	}
+
+	if n == "" {
+		return "global::"
+	}
+
+	return "global::" + n + "."
+}
+
+// toPascal converts the given name into pascal case the same way the csharp
+// plugin names generated classes, e.g. "UserGroup" for "user_group".
+func toPascal(s string) string {
+	f := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+
+	var n string
+	for _, x := range strings.FieldsFunc(s, f) {
+		r := []rune(x)
+		r[0] = unicode.ToUpper(r[0])
+		n += string(r)
+	}
+
+	return n
+}
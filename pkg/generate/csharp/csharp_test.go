@@ -0,0 +1,447 @@
+package csharp
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_CSharp_Commands tests the protoc command generation. The protoc
+// binary is used to generate language specific code based on a gRPC apischema.
+// The generated protoc commands are executed in order to generate the actual
+// language specific code. The tests here ensure that the command execution with
+// its flags and positional arguments works as expected.
+//
+//	go test ./pkg/generate/csharp -run Test_CSharp_Commands -update
+func Test_CSharp_Commands(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		dst string
+		src string
+	}{
+		// Case 0 ensures that a single proto file in a single directory is
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf")
+				mustCreateFile(fs, "pbf/foo.proto")
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: ".",
+		},
+		// Case 1 ensures that a single proto file in a single directory is
+		// scanned accordingly. Note that there are additional empty
+		// directories.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf")
+				mustCreateFile(fs, "pbf/foo.proto")
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateDir(fs, "pbf/user")
+
+				return fs
+			}(),
+			dst: "some/other/dir",
+			src: ".",
+		},
+		// Case 2 ensures that multiple proto files in multiple directories are
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: ".",
+		},
+		// Case 3 ensures that multiple proto files in multiple directories are
+		// scanned accordingly. Note that the given destination path is slightly
+		// different.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				return fs
+			}(),
+			dst: "gen",
+			src: ".",
+		},
+		// Case 4 ensures that multiple proto files in multiple directories are
+		// scanned accordingly. Note that the given destination path is
+		// absolute.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				mustCreateDir(fs, "pbf/more/deeply/nested")
+				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+
+				return fs
+			}(),
+			dst: "/home/runner/tmp/gen/",
+			src: ".",
+		},
+		// Case 5 ensures that only proto files in the source directory are
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				mustCreateDir(fs, "pbf/more/deeply/nested")
+				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: "./pbf/user/",
+		},
+		// Case 6 ensures that every resource directory is generated into its
+		// own directory within the destination and that the grpc plugin is
+		// mapped to grpc_csharp_plugin, which is how the Grpc.Tools package
+		// ships protoc-gen-grpc.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "api/user/api.proto", "syntax = \"proto3\";\n\nservice API {}\n")
+				mustCreateProto(fs, "api/user/search.proto", "syntax = \"proto3\";\n\nmessage SearchI {}\n")
+
+				return fs
+			}(),
+			dst: "Generated",
+			src: "api",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var g generate.Interface
+			{
+				c := Config{
+					FileSystem: tc.fs,
+
+					Destination: tc.dst,
+					Project:     "Api",
+					Source:      tc.src,
+				}
+
+				g, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := g.Commands()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual string
+			{
+				var s []string
+				for _, c := range l {
+					s = append(s, c.String())
+				}
+
+				sort.Strings(s)
+
+				actual = strings.Join(s, "\n") + "\n"
+			}
+
+			p := filepath.Join("testdata/commands", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, []byte(actual), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, []byte(actual)) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_CSharp_Files tests the additional file generation. CSharp gets a project
+// file and one static class per resource aggregating the generated types. The tests here
+// ensure that the additional file generation works according to the gRPC api
+// schema.
+//
+//	go test ./pkg/generate/csharp -run Test_CSharp_Files -update
+func Test_CSharp_Files(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		dst string
+		grp string
+		prb string
+		prj string
+		src string
+	}{
+		// Case 0 ensures that the generated types are qualified according to
+		// the namespace the csharp plugin derives from the package. Note that
+		// the resource class is named after the resource directory.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/user-group/api.proto", "syntax = \"proto3\";\n\npackage pbf.user_group;\n\nservice API {\n  rpc Search(SearchI) returns (SearchO);\n}\n")
+				mustCreateProto(fs, "pbf/user-group/search.proto", "syntax = \"proto3\";\n\npackage pbf.user_group;\n\nmessage SearchI {}\nmessage SearchO {}\n")
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: ".",
+		},
+		// Case 1 ensures that the csharp_namespace option takes precedence
+		// over the package.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\npackage pbf.user;\n\noption csharp_namespace = \"Acme.Users\";\n\nservice API {\n  rpc Create(CreateI) returns (CreateO);\n}\n")
+				mustCreateProto(fs, "pbf/user/create.proto", "syntax = \"proto3\";\n\npackage pbf.user;\n\noption csharp_namespace = \"Acme.Users\";\n\nmessage CreateI {}\nmessage CreateO {}\n")
+
+				return fs
+			}(),
+			dst: "gen",
+			src: ".",
+		},
+		// Case 2 ensures that the generated types live in the global namespace
+		// if the csharp_namespace option is empty or if neither the option
+		// nor the package is defined.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/post/create.proto", "syntax = \"proto3\";\n\npackage pbf.post;\n\noption csharp_namespace = \"\";\n\nmessage CreateI {}\nmessage CreateO {}\n")
+				mustCreateProto(fs, "pbf/post/delete.proto", "syntax = \"proto3\";\n\nmessage DeleteI {}\nmessage DeleteO {}\n")
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: ".",
+		},
+		// Case 3 ensures that the configured project and package versions are
+		// applied, that the clients of multiple services are keyed by their
+		// service names and that neither members nor classes are named like
+		// their enclosing class.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/post/api.proto", "syntax = \"proto3\";\n\npackage pbf.post;\n\nservice Reader {\n  rpc Search(PostI) returns (PostO);\n}\n\nservice Writer {\n  rpc Create(PostI) returns (PostO);\n}\n")
+				mustCreateProto(fs, "pbf/post/post.proto", "syntax = \"proto3\";\n\npackage pbf.post;\n\nmessage Post {}\nmessage PostI {}\nmessage PostO {}\n")
+
+				return fs
+			}(),
+			dst: "/home/runner/tmp/gen/",
+			grp: "2.60.0",
+			prb: "3.21.12",
+			prj: "Acme.Api",
+			src: ".",
+		},
+		// Case 4 ensures that multiple services, enums and messages not
+		// following the naming conventions are aggregated as well. Note that
+		// the generated types are qualified according to their namespace.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/user/admin.proto", `
+syntax = "proto3";
+
+option csharp_namespace = "Admin";
+
+service Admin {
+  rpc Ban(BanI) returns (BanO);
+}
+
+message BanI {}
+message BanO {}
+`)
+				mustCreateProto(fs, "pbf/user/api.proto", `
+syntax = "proto3";
+
+service API {
+  rpc Create(CreateI) returns (CreateO);
+}
+`)
+				mustCreateProto(fs, "pbf/user/create.proto", `
+syntax = "proto3";
+
+package pbf.user_group;
+
+message CreateI {
+  Kind kind = 1;
+}
+
+message CreateO {}
+
+message Obj {}
+
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+}
+`)
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: ".",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var g generate.Interface
+			{
+				c := Config{
+					FileSystem: tc.fs,
+
+					Destination: tc.dst,
+					Grpc:        tc.grp,
+					Project:     "Api",
+					Protobuf:    tc.prb,
+					Source:      tc.src,
+				}
+
+				if tc.prj != "" {
+					c.Project = tc.prj
+				}
+
+				g, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := g.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual string
+			{
+				var s []string
+				for _, f := range l {
+					s = append(s, string(f.Bytes))
+					s = append(s, f.Path)
+				}
+
+				actual = strings.Join(s, "\n") + "\n"
+			}
+
+			p := filepath.Join("testdata/files", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, []byte(actual), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, []byte(actual)) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
+
+func mustCreateDir(fs afero.Fs, p string) {
+	err := fs.MkdirAll(p, 0755)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustCreateFile(fs afero.Fs, p string) {
+	err := afero.WriteFile(fs, p, nil, 0644)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustCreateProto(fs afero.Fs, p string, s string) {
+	err := afero.WriteFile(fs, p, []byte(s), 0644)
+	if err != nil {
+		panic(err)
+	}
+}
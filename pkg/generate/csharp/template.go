@@ -0,0 +1,62 @@
+package csharp
+
+const projectTemplate = `<!--
+  Do not edit. This file was generated via the "pag" command line tool. More
+  information about the tool can be found at github.com/xh3b4sd/pag.
+
+      pag generate csharp
+-->
+<Project Sdk="Microsoft.NET.Sdk">
+
+  <PropertyGroup>
+    <TargetFramework>netstandard2.1</TargetFramework>
+    <RootNamespace>{{ .Project }}</RootNamespace>
+    <AssemblyName>{{ .Project }}</AssemblyName>
+  </PropertyGroup>
+
+  <ItemGroup>
+    <PackageReference Include="Google.Protobuf" Version="{{ .Protobuf }}" />
+    <PackageReference Include="Grpc.Core.Api" Version="{{ .Grpc }}" />
+  </ItemGroup>
+
+</Project>
+`
+
+const resourceTemplate = `//
+// Do not edit. This file was generated via the "pag" command line tool. More
+// information about the tool can be found at github.com/xh3b4sd/pag.
+//
+//     pag generate csharp
+//
+
+namespace {{ .Namespace }}
+{
+    public static class {{ .Name }}
+    {
+        public static global::System.Collections.Generic.IReadOnlyList<global::Google.Protobuf.Reflection.FileDescriptor> Descriptors { get; } = new[]
+        {
+{{- range $d := .Descriptors }}
+            {{ $d }}.Descriptor,
+{{- end }}
+        };
+{{- range $c := .Clients }}
+
+        public static {{ $c.Name }} {{ $c.Key }}(global::Grpc.Core.ChannelBase channel) => new {{ $c.Name }}(channel);
+{{- end }}
+{{- if .Members }}
+{{ range $m := .Members }}
+        public static {{ $m.Name }} {{ $m.Key }}() => new {{ $m.Name }}();
+{{- end }}
+{{- end }}
+{{- range $g := .Groups }}
+
+        public static class {{ $g.Key }}
+        {
+{{- range $m := $g.Members }}
+            public static {{ $m.Name }} {{ $m.Key }}() => new {{ $m.Name }}();
+{{- end }}
+        }
+{{- end }}
+    }
+}
+`
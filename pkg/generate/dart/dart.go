@@ -0,0 +1,324 @@
+package dart
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+	"github.com/xh3b4sd/pag/pkg/schema"
+)
+
+const (
+	Binary = "protoc"
+	// Arg is the specific argument string required in order to generate dart
+	// code based on a gRPC api schema. The grpc parameter causes the dart
+	// plugin to generate clients and services in addition to the messages.
+	Arg = "--experimental_allow_proto3_optional --dart_out=grpc:%s --proto_path=%s %s"
+)
+
+type Config struct {
+	FileSystem afero.Fs
+	Schema     *schema.Schema
+
+	Destination string
+	Exclude     []string
+	Source      string
+}
+
+type Dart struct {
+	schema *schema.Schema
+
+	destination string
+	source      string
+}
+
+func New(config Config) (*Dart, error) {
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
+
+	if config.Destination == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Destination must not be empty", config)
+	}
+	if config.Source == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Source must not be empty", config)
+	}
+
+	var err error
+
+	s := config.Schema
+	if s == nil {
+		c := schema.Config{
+			FileSystem: config.FileSystem,
+
+			Exclude: config.Exclude,
+			Source:  config.Source,
+		}
+
+		s, err = schema.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	d := &Dart{
+		schema: s,
+
+		destination: config.Destination,
+		source:      config.Source,
+	}
+
+	return d, nil
+}
+
+func (d *Dart) Commands() ([]generate.Command, error) {
+	dirs, err := d.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var cmds []generate.Command
+	for _, x := range dirs {
+		c := func(f string) generate.Command {
+			return generate.Command{
+				Binary:    Binary,
+				Arguments: strings.Split(fmt.Sprintf(f, d.destination, d.source, strings.Join(x.Paths(), " ")), " "),
+				Directory: d.destination,
+			}
+		}
+
+		cmds = append(cmds, c(Arg))
+	}
+
+	return cmds, nil
+}
+
+// Files returns a single barrel library "index.dart" exporting all libraries
+// generated for all resources, so that e.g. all messages and clients can be
+// imported via "index.dart". The conventional message names like CreateI are
+// defined by many resources, but dart does not allow exporting the same name
+// from different libraries. Conflicting names are therefore hidden from the
+// exports and made available as type aliases prefixed with their resource
+// instead, e.g. UserCreateI and PostCreateI, which requires dart 2.13.
+func (d *Dart) Files() ([]generate.File, error) {
+	dirs, err := d.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	type Library struct {
+		// Alias is the import prefix of the library, only used if the library
+		// defines conflicting names.
+		Alias string
+		// Names are all names defined by the library.
+		Names []string
+		// Path is the location of the library relative to the barrel
+		// library, e.g. "user/create.pb.dart".
+		Path string
+		// Resource is the type name of the resource defining the library,
+		// e.g. "User".
+		Resource string
+	}
+
+	// The generated ".pb.dart" libraries export their respective
+	// ".pbenum.dart" libraries already. The generated ".pbgrpc.dart"
+	// libraries only exist for proto files defining services. They export
+	// their respective ".pb.dart" libraries, which is why conflicting names of
+	// the latter have to be hidden from the former as well.
+	var libs []Library
+	var deps [][]int
+	for _, x := range dirs {
+		rel, err := filepath.Rel(d.source, x.Path)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		for _, f := range x.Files {
+			p := filepath.ToSlash(filepath.Join(rel, f.Base()))
+			a := toFile(x.Resource()) + "_" + toFile(f.Base())
+
+			libs = append(libs, Library{
+				Alias:    a + "_pb",
+				Names:    messages(f),
+				Path:     p + ".pb.dart",
+				Resource: toType(x.Resource()),
+			})
+			deps = append(deps, []int{len(libs) - 1})
+
+			if len(f.Services) != 0 {
+				libs = append(libs, Library{
+					Alias:    a + "_pbgrpc",
+					Names:    services(f),
+					Path:     p + ".pbgrpc.dart",
+					Resource: toType(x.Resource()),
+				})
+				deps = append(deps, []int{len(libs) - 2, len(libs) - 1})
+			}
+		}
+	}
+
+	cnt := map[string]int{}
+	for _, l := range libs {
+		for _, n := range l.Names {
+			cnt[n]++
+		}
+	}
+
+	type Alias struct {
+		Import string
+		Name   string
+		Type   string
+	}
+
+	type Export struct {
+		Hide string
+		Path string
+	}
+
+	type Import struct {
+		Alias string
+		Path  string
+	}
+
+	type Barrel struct {
+		Aliases []Alias
+		Exports []Export
+		Imports []Import
+	}
+
+	var b Barrel
+	for i, l := range libs {
+		var hid []string
+		for _, j := range deps[i] {
+			for _, n := range libs[j].Names {
+				if cnt[n] > 1 {
+					hid = append(hid, n)
+				}
+			}
+		}
+
+		sort.Strings(hid)
+
+		b.Exports = append(b.Exports, Export{Hide: strings.Join(hid, ", "), Path: l.Path})
+
+		var imp bool
+		for _, n := range l.Names {
+			if cnt[n] > 1 {
+				b.Aliases = append(b.Aliases, Alias{Import: l.Alias, Name: l.Resource + n, Type: n})
+				imp = true
+			}
+		}
+
+		if imp {
+			b.Imports = append(b.Imports, Import{Alias: l.Alias, Path: l.Path})
+		}
+	}
+
+	f := generate.File{
+		Path: filepath.Join(d.destination, "index.dart"),
+	}
+
+	f.Bytes, err = d.render(f.Path, barrelTemplate, b)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	return []generate.File{f}, nil
+}
+
+// messages returns the names of all classes the ".pb.dart" library of the
+// given file defines for messages and enums, including nested ones, e.g.
+// "User_Kind" for the enum Kind nested in the message User.
+func messages(f schema.File) []string {
+	var l []string
+	for _, e := range f.Enums {
+		l = append(l, e.Name)
+	}
+	for _, m := range f.Messages {
+		l = append(l, message("", m)...)
+	}
+
+	return l
+}
+
+func message(prefix string, m schema.Message) []string {
+	n := prefix + m.Name
+
+	l := []string{n}
+	for _, e := range m.Enums {
+		l = append(l, n+"_"+e.Name)
+	}
+	for _, o := range m.Oneofs {
+		l = append(l, n+"_"+toType(o.Name))
+	}
+	for _, x := range m.Messages {
+		l = append(l, message(n+"_", x)...)
+	}
+
+	return l
+}
+
+// services returns the names of all classes the ".pbgrpc.dart" library of the
+// given file defines for services, e.g. "APIClient" and "APIServiceBase".
+func services(f schema.File) []string {
+	var l []string
+	for _, s := range f.Services {
+		l = append(l, s.Name+"Client", s.Name+"ServiceBase")
+	}
+
+	return l
+}
+
+func (d *Dart) render(path string, tmpl string, data interface{}) ([]byte, error) {
+	s, err := template.New(path).Parse(tmpl)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var b bytes.Buffer
+	err = s.ExecuteTemplate(&b, path, data)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// toFile converts the given name into a valid dart file name, e.g.
+// "user_group" for "user-group".
+func toFile(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+
+	return b.String()
+}
+
+// toType converts the given name into a valid dart type name, e.g.
+// "UserGroup" for "user-group" and "TestOneof" for "test_oneof".
+func toType(s string) string {
+	f := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+
+	var b strings.Builder
+	for _, x := range strings.FieldsFunc(s, f) {
+		r := []rune(x)
+		b.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+	}
+
+	return b.String()
+}
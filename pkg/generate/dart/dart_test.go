@@ -0,0 +1,433 @@
+package dart
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_Dart_Commands tests the protoc command generation. The protoc
+// binary is used to generate language specific code based on a gRPC apischema.
+// The generated protoc commands are executed in order to generate the actual
+// language specific code. The tests here ensure that the command execution with
+// its flags and positional arguments works as expected.
+//
+//	go test ./pkg/generate/dart -run Test_Dart_Commands -update
+func Test_Dart_Commands(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		dst string
+		src string
+	}{
+		// Case 0 ensures that a single proto file in a single directory is
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf")
+				mustCreateFile(fs, "pbf/foo.proto")
+
+				return fs
+			}(),
+			dst: "./lib/",
+			src: ".",
+		},
+		// Case 1 ensures that a single proto file in a single directory is
+		// scanned accordingly. Note that there are additional empty
+		// directories.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf")
+				mustCreateFile(fs, "pbf/foo.proto")
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateDir(fs, "pbf/user")
+
+				return fs
+			}(),
+			dst: "some/other/dir",
+			src: ".",
+		},
+		// Case 2 ensures that multiple proto files in multiple directories are
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				return fs
+			}(),
+			dst: "./lib/",
+			src: ".",
+		},
+		// Case 3 ensures that multiple proto files in multiple directories are
+		// scanned accordingly. Note that the given destination path is slightly
+		// different.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				return fs
+			}(),
+			dst: "lib",
+			src: ".",
+		},
+		// Case 4 ensures that multiple proto files in multiple directories are
+		// scanned accordingly. Note that the given destination path is
+		// absolute.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				mustCreateDir(fs, "pbf/more/deeply/nested")
+				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+
+				return fs
+			}(),
+			dst: "/home/runner/tmp/lib/",
+			src: ".",
+		},
+		// Case 5 ensures that only proto files in the source directory are
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				mustCreateDir(fs, "pbf/more/deeply/nested")
+				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+
+				return fs
+			}(),
+			dst: "./lib/",
+			src: "./pbf/user/",
+		},
+		// Case 6 ensures that a single command per resource directory
+		// generates messages and clients at once, by passing the grpc
+		// parameter to protoc-gen-dart, e.g. "--dart_out=grpc:lib/src/".
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\nservice API {}\n")
+				mustCreateProto(fs, "pbf/user/search.proto", "syntax = \"proto3\";\n\nmessage SearchI {}\n")
+
+				return fs
+			}(),
+			dst: "lib/src/",
+			src: ".",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var g generate.Interface
+			{
+				c := Config{
+					FileSystem: tc.fs,
+
+					Destination: tc.dst,
+					Source:      tc.src,
+				}
+
+				g, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := g.Commands()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual string
+			{
+				var s []string
+				for _, c := range l {
+					s = append(s, c.String())
+				}
+
+				sort.Strings(s)
+
+				actual = strings.Join(s, "\n") + "\n"
+			}
+
+			p := filepath.Join("testdata/commands", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, []byte(actual), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, []byte(actual)) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Dart_Files tests the additional file generation. Dart gets one barrel
+// library per resource so that the generated code can be imported at once. The tests here
+// ensure that the additional file generation works according to the gRPC api
+// schema.
+//
+//	go test ./pkg/generate/dart -run Test_Dart_Files -update
+func Test_Dart_Files(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		dst string
+		src string
+	}{
+		// Case 0 ensures that libraries are exported from the paths protoc
+		// generates them into, which keep the directory names as they are.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/User-Group/api.proto", "syntax = \"proto3\";\n\nservice API {\n  rpc Search(SearchI) returns (SearchO);\n}\n")
+				mustCreateProto(fs, "pbf/User-Group/search.proto", "syntax = \"proto3\";\n\nmessage SearchI {}\nmessage SearchO {}\n")
+
+				return fs
+			}(),
+			dst: "./lib/",
+			src: ".",
+		},
+		// Case 1 ensures that grpc libraries are only exported for proto files
+		// defining services, independent of the file name. Note that the api
+		// file here does not define any service while the admin file does.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/user/admin.proto", "syntax = \"proto3\";\n\nservice Admin {\n  rpc Delete(DeleteI) returns (DeleteO);\n}\n\nmessage DeleteI {}\nmessage DeleteO {}\n")
+				mustCreateProto(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\nmessage SearchI {}\nmessage SearchO {}\n")
+
+				return fs
+			}(),
+			dst: "lib",
+			src: ".",
+		},
+		// Case 2 ensures that libraries of deeply nested resources are
+		// exported with their complete directory path.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/more/deeply/nested/post.proto", "syntax = \"proto3\";\n\nmessage Post {}\n")
+				mustCreateProto(fs, "pbf/user/user.proto", "syntax = \"proto3\";\n\nmessage User {}\n")
+
+				return fs
+			}(),
+			dst: "/home/runner/tmp/lib/",
+			src: ".",
+		},
+		// Case 3 ensures that libraries are exported relative to the source
+		// directory, since protoc generates them relative to it.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/post/post.proto", "syntax = \"proto3\";\n\nmessage Post {}\n")
+				mustCreateProto(fs, "pbf/user/user.proto", "syntax = \"proto3\";\n\nmessage User {}\n")
+
+				return fs
+			}(),
+			dst: "./lib/",
+			src: "./pbf/",
+		},
+		// Case 4 ensures that names defined by multiple resources are hidden
+		// from the exports and aliased with their resource instead, including
+		// nested messages, enums and oneofs as well as service classes.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/post/api.proto", "syntax = \"proto3\";\n\nservice API {\n  rpc Create(CreateI) returns (CreateO);\n}\n")
+				mustCreateProto(fs, "pbf/post/create.proto", "syntax = \"proto3\";\n\nmessage CreateI {\n  message Obj {}\n}\nmessage CreateO {}\n")
+				mustCreateProto(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\nservice API {\n  rpc Create(CreateI) returns (CreateO);\n}\n")
+				mustCreateProto(fs, "pbf/user/create.proto", "syntax = \"proto3\";\n\nenum Kind {\n  KIND_UNSPECIFIED = 0;\n}\n\nmessage CreateI {\n  message Obj {\n    enum Role {\n      ROLE_UNSPECIFIED = 0;\n    }\n  }\n  oneof by_name {\n    string name = 1;\n  }\n}\nmessage CreateO {}\n")
+
+				return fs
+			}(),
+			dst: "./lib/",
+			src: "./pbf/",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var g generate.Interface
+			{
+				c := Config{
+					FileSystem: tc.fs,
+
+					Destination: tc.dst,
+					Source:      tc.src,
+				}
+
+				g, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := g.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual string
+			{
+				var s []string
+				for _, f := range l {
+					s = append(s, string(f.Bytes))
+					s = append(s, f.Path)
+				}
+
+				actual = strings.Join(s, "\n") + "\n"
+			}
+
+			p := filepath.Join("testdata/files", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, []byte(actual), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, []byte(actual)) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Dart_toType tests the conversion of resource and oneof names into dart
+// type names. The tests here ensure that every word is capitalized and that
+// non alphanumeric characters are removed.
+func Test_Dart_toType(t *testing.T) {
+	testCases := []struct {
+		s string
+		t string
+	}{
+		// Case 0 ensures that single words are capitalized.
+		{
+			s: "user",
+			t: "User",
+		},
+		// Case 1 ensures that words separated by dashes and underscores are
+		// capitalized and joined.
+		{
+			s: "user-group_member",
+			t: "UserGroupMember",
+		},
+		// Case 2 ensures that upper case letters and digits are kept.
+		{
+			s: "API.v1.2",
+			t: "APIV12",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s := toType(tc.s)
+			if s != tc.t {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.t, s))
+			}
+		})
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
+
+func mustCreateDir(fs afero.Fs, p string) {
+	err := fs.MkdirAll(p, 0755)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustCreateFile(fs afero.Fs, p string) {
+	err := afero.WriteFile(fs, p, nil, 0644)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustCreateProto(fs afero.Fs, p string, s string) {
+	err := afero.WriteFile(fs, p, []byte(s), 0644)
+	if err != nil {
+		panic(err)
+	}
+}
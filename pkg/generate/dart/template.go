@@ -0,0 +1,22 @@
+package dart
+
+const barrelTemplate = `//
+// Do not edit. This file was generated via the "pag" command line tool. More
+// information about the tool can be found at github.com/xh3b4sd/pag.
+//
+//     pag generate dart
+//
+{{- if .Imports }}
+{{ range $i := .Imports }}
+import '{{ $i.Path }}' as {{ $i.Alias }};
+{{- end }}
+{{- end }}
+{{ range $e := .Exports }}
+export '{{ $e.Path }}'{{ if $e.Hide }} hide {{ $e.Hide }}{{ end }};
+{{- end }}
+{{- if .Aliases }}
+{{ range $a := .Aliases }}
+typedef {{ $a.Name }} = {{ $a.Import }}.{{ $a.Type }};
+{{- end }}
+{{- end }}
+`
@@ -1,8 +1,9 @@
 package golang
 
 import (
+	"embed"
 	"fmt"
-	"os"
+	"io/fs"
 	"path/filepath"
 	"strings"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/xh3b4sd/tracer"
 
 	"github.com/xh3b4sd/pag/pkg/generate"
+	"github.com/xh3b4sd/pag/pkg/schema"
 )
 
 const (
@@ -24,19 +26,73 @@ const (
 	// generation process is separate due to API changes and migration efforts
 	// in the upstream gRPC ecosystem.
 	SvcArg = "--experimental_allow_proto3_optional --go-grpc_out=%s/ --proto_path=%s %s"
+	// ConnectArg is the specific argument string required in order to generate
+	// go handlers and clients based on gRPC services using Connect. The
+	// generated code is put into the same destination as the generated go
+	// structs, where protoc-gen-connect-go creates the respective *connect
+	// packages.
+	ConnectArg = "--experimental_allow_proto3_optional --connect-go_out=%s/ --proto_path=%s %s"
+	// GatewayArg is the specific argument string required in order to generate
+	// go reverse proxies translating REST calls into gRPC calls based on the
+	// google.api.http annotations of gRPC services.
+	GatewayArg = "--experimental_allow_proto3_optional --grpc-gateway_out=%s/ --proto_path=%s %s"
+	// OpenAPIArg is the specific argument string required in order to generate
+	// OpenAPI v2 specifications based on the google.api.http annotations of
+	// gRPC services.
+	OpenAPIArg = "--experimental_allow_proto3_optional --openapiv2_out=%s/ --proto_path=%s %s"
+	// Stage is the directory within the destination the schema files of the
+	// google.api.http annotations are staged in when generating gateways.
+	// Note that staged files are only inputs of the commands and never
+	// installed into the destination.
+	Stage = ".pag/proto"
+)
+
+// googleapis provides the schema files of the google.api.http annotations,
+// google/api/annotations.proto and google/api/http.proto, so that gateways can
+// be generated without any third party include.
+//
+//go:embed proto
+var googleapis embed.FS
+
+const (
+	// ServiceConnect configures the generation of Connect handlers and
+	// clients via protoc-gen-connect-go.
+	ServiceConnect = "connect"
+	// ServiceGRPC configures the generation of gRPC servers and clients via
+	// protoc-gen-go-grpc.
+	ServiceGRPC = "grpc"
 )
 
 type Config struct {
 	FileSystem afero.Fs
+	Schema     *schema.Schema
 
 	Destination string
-	Source      string
+	// Exclude is the list of files and directories to ignore while scanning
+	// the source.
+	Exclude []string
+	// Gateway configures the additional generation of grpc-gateway reverse
+	// proxies and OpenAPI v2 specifications.
+	Gateway bool
+	// Includes is the list of additional directories protoc looks for
+	// imported schema definitions. Note that "google/api/annotations.proto"
+	// and "google/api/http.proto" are provided for gateways already, unless
+	// they are found within any of the includes.
+	Includes []string
+	// Services is the list of service implementations to generate for gRPC
+	// services, e.g. "grpc" and "connect". Both can be generated side by side
+	// during migrations from one to the other. Defaults to "grpc".
+	Services []string
+	Source   string
 }
 
 type Golang struct {
-	fileSystem afero.Fs
+	schema *schema.Schema
 
 	destination string
+	gateway     bool
+	includes    []string
+	services    []string
 	source      string
 }
 
@@ -48,14 +104,42 @@ func New(config Config) (*Golang, error) {
 	if config.Destination == "" {
 		return nil, tracer.Maskf(invalidConfigError, "%T.Destination must not be empty", config)
 	}
+	if len(config.Services) == 0 {
+		config.Services = []string{ServiceGRPC}
+	}
+	for _, s := range config.Services {
+		if s != ServiceConnect && s != ServiceGRPC {
+			return nil, tracer.Maskf(invalidConfigError, "%T.Services must only contain %q or %q but got %q", config, ServiceConnect, ServiceGRPC, s)
+		}
+	}
 	if config.Source == "" {
 		return nil, tracer.Maskf(invalidConfigError, "%T.Source must not be empty", config)
 	}
 
+	var err error
+
+	s := config.Schema
+	if s == nil {
+		c := schema.Config{
+			FileSystem: config.FileSystem,
+
+			Exclude: config.Exclude,
+			Source:  config.Source,
+		}
+
+		s, err = schema.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
 	g := &Golang{
-		fileSystem: config.FileSystem,
+		schema: s,
 
 		destination: config.Destination,
+		gateway:     config.Gateway,
+		includes:    config.Includes,
+		services:    config.Services,
 		source:      config.Source,
 	}
 
@@ -63,73 +147,112 @@ func New(config Config) (*Golang, error) {
 }
 
 func (g *Golang) Commands() ([]generate.Command, error) {
-	dirs, err := g.dirs(".proto")
+	dirs, err := g.schema.Directories()
 	if err != nil {
 		return nil, tracer.Mask(err)
 	}
 
 	var cmds []generate.Command
-	for d, l := range dirs {
+	for _, d := range dirs {
+		l := d.Paths()
+
 		c := func(f string) generate.Command {
-			return generate.Command{
-				Binary:    Binary,
-				Arguments: strings.Split(fmt.Sprintf(f, filepath.Join(g.destination, d), g.source, strings.Join(l, " ")), " "),
-				Directory: filepath.Join(g.destination, d),
-			}
+			return g.command(f, filepath.Join(g.destination, d.Path), l)
 		}
 
 		cmds = append(cmds, c(MsgArg))
-		cmds = append(cmds, c(SvcArg))
+
+		for _, s := range g.services {
+			if s == ServiceConnect {
+				cmds = append(cmds, c(ConnectArg))
+			}
+			if s == ServiceGRPC {
+				cmds = append(cmds, c(SvcArg))
+			}
+		}
+
+		if g.gateway {
+			cmds = append(cmds, c(GatewayArg))
+			// The OpenAPI specifications are written relative to the schema
+			// paths, which is why they are generated into the destination
+			// directly, e.g. "pkg/pbf/user/api.swagger.json".
+			cmds = append(cmds, g.command(OpenAPIArg, filepath.Clean(g.destination), l))
+		}
 	}
 
 	return cmds, nil
 }
 
-func (g *Golang) Files() ([]generate.File, error) {
-	return nil, nil
-}
+// command returns the protoc command for the given argument format, output
+// directory and schema files. Configured includes are added as additional
+// proto paths so that third party imports can be resolved. The staged schema
+// files of gateways come last, so that configured includes take precedence.
+func (g *Golang) command(f string, out string, files []string) generate.Command {
+	args := strings.Split(fmt.Sprintf(f, out, g.source, strings.Join(files, " ")), " ")
+
+	var inc []string
+	for _, i := range g.includes {
+		inc = append(inc, "--proto_path="+i)
+	}
+
+	if g.gateway {
+		inc = append(inc, "--proto_path="+filepath.Join(g.destination, Stage))
+	}
 
-func (g *Golang) dirs(exts ...string) (map[string][]string, error) {
-	dirs := map[string][]string{}
 	{
-		walkFunc := func(p string, i os.FileInfo, err error) error {
-			if err != nil {
-				return tracer.Mask(err)
-			}
+		i := len(args) - len(files)
+		args = append(args[:i], append(inc, args[i:]...)...)
+	}
 
-			if i.IsDir() && i.Name() == ".git" {
-				return filepath.SkipDir
-			}
+	return generate.Command{
+		Binary:    Binary,
+		Arguments: args,
+		Directory: out,
+	}
+}
 
-			if i.IsDir() && i.Name() == ".github" {
-				return filepath.SkipDir
-			}
+func (g *Golang) Files() ([]generate.File, error) {
+	return nil, nil
+}
 
-			// We do not want to track directories. We are interested in
-			// directories containing specific files.
-			if i.IsDir() {
-				return nil
-			}
+// Stage returns the schema files of the google.api.http annotations if
+// gateways are generated, so that the annotations can be imported without
+// configuring any include.
+func (g *Golang) Stage() ([]generate.File, error) {
+	if !g.gateway {
+		return nil, nil
+	}
 
-			// We do not want to track files with the wrong extension. We are
-			// interested in protocol buffer files having the ".proto"
-			// extension.
-			for _, e := range exts {
-				if filepath.Ext(i.Name()) != e {
-					return nil
-				}
-			}
+	var l []generate.File
 
-			dirs[filepath.Dir(p)] = append(dirs[filepath.Dir(p)], filepath.Join(filepath.Dir(p), i.Name()))
+	walkFunc := func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return tracer.Mask(err)
+		}
 
+		if e.IsDir() {
 			return nil
 		}
 
-		err := afero.Walk(g.fileSystem, g.source, walkFunc)
+		b, err := googleapis.ReadFile(p)
 		if err != nil {
-			return nil, tracer.Mask(err)
+			return tracer.Mask(err)
+		}
+
+		rel, err := filepath.Rel("proto", filepath.FromSlash(p))
+		if err != nil {
+			return tracer.Mask(err)
 		}
+
+		l = append(l, generate.File{Path: filepath.Join(g.destination, Stage, rel), Bytes: b})
+
+		return nil
+	}
+
+	err := fs.WalkDir(googleapis, "proto", walkFunc)
+	if err != nil {
+		return nil, tracer.Mask(err)
 	}
 
-	return dirs, nil
+	return l, nil
 }
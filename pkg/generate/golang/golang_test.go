@@ -5,6 +5,7 @@ import (
 	"flag"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -24,12 +25,14 @@ var update = flag.Bool("update", false, "update .golden files")
 // language specific code. The tests here ensure that the command execution with
 // its flags and positional arguments works as expected.
 //
-//     go test ./pkg/generate/golang -run Test_Golang_Commands -update
-//
+//	go test ./pkg/generate/golang -run Test_Golang_Commands -update
 func Test_Golang_Commands(t *testing.T) {
 	testCases := []struct {
 		fs  afero.Fs
 		dst string
+		gwy bool
+		inc []string
+		svc []string
 		src string
 	}{
 		// Case 0 ensures that a single proto file in a single directory is
@@ -156,6 +159,81 @@ func Test_Golang_Commands(t *testing.T) {
 			dst: "./pkg/",
 			src: "./pbf/user/",
 		},
+		// Case 6 ensures that Connect handlers and clients can be generated
+		// instead of gRPC servers and clients.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+
+				return fs
+			}(),
+			dst: "./pkg/",
+			svc: []string{ServiceConnect},
+			src: ".",
+		},
+		// Case 7 ensures that Connect handlers and clients can be generated
+		// in addition to gRPC servers and clients.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+
+				return fs
+			}(),
+			dst: "./pkg/",
+			svc: []string{ServiceGRPC, ServiceConnect},
+			src: ".",
+		},
+		// Case 8 ensures that grpc-gateway reverse proxies and OpenAPI
+		// specifications can be generated using third party includes, which
+		// take precedence over the staged google.api schema files.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+
+				return fs
+			}(),
+			dst: "./pkg/",
+			gwy: true,
+			inc: []string{"third_party/googleapis", "/usr/local/include"},
+			src: ".",
+		},
+		// Case 9 ensures that grpc-gateway reverse proxies and OpenAPI
+		// specifications can be generated without any third party include,
+		// using the staged google.api schema files.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/api.proto")
+
+				return fs
+			}(),
+			dst: "./pkg/",
+			gwy: true,
+			src: ".",
+		},
 	}
 
 	for i, tc := range testCases {
@@ -168,6 +246,9 @@ func Test_Golang_Commands(t *testing.T) {
 					FileSystem: tc.fs,
 
 					Destination: tc.dst,
+					Gateway:     tc.gwy,
+					Includes:    tc.inc,
+					Services:    tc.svc,
 					Source:      tc.src,
 				}
 
@@ -214,6 +295,74 @@ func Test_Golang_Commands(t *testing.T) {
 	}
 }
 
+// Test_Golang_Stage tests the schema files staged for the commands of the
+// golang generator. The tests here ensure that the schema files of the
+// google.api.http annotations are only staged for gateways.
+func Test_Golang_Stage(t *testing.T) {
+	testCases := []struct {
+		gwy bool
+		dst string
+		pat []string
+	}{
+		// Case 0 ensures that nothing is staged without gateways.
+		{
+			gwy: false,
+			dst: "./pkg/",
+			pat: nil,
+		},
+		// Case 1 ensures that the google.api schema files are staged within
+		// the destination for gateways.
+		{
+			gwy: true,
+			dst: "./pkg/",
+			pat: []string{
+				"pkg/.pag/proto/google/api/annotations.proto",
+				"pkg/.pag/proto/google/api/http.proto",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var g *Golang
+			{
+				c := Config{
+					FileSystem: afero.NewMemMapFs(),
+
+					Destination: tc.dst,
+					Gateway:     tc.gwy,
+					Source:      ".",
+				}
+
+				g, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := g.Stage()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var pat []string
+			for _, f := range l {
+				if !bytes.Contains(f.Bytes, []byte("package google.api;")) {
+					t.Fatalf("expected %s to define package google.api", f.Path)
+				}
+
+				pat = append(pat, f.Path)
+			}
+
+			if !reflect.DeepEqual(tc.pat, pat) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.pat, pat))
+			}
+		})
+	}
+}
+
 func fileName(i int) string {
 	return "case-" + strconv.Itoa(i) + ".golden"
 }
@@ -0,0 +1,274 @@
+package java
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+	"github.com/xh3b4sd/pag/pkg/schema"
+)
+
+const (
+	Binary = "protoc"
+	// KtArg is the specific argument string required in order to generate
+	// kotlin DSL builders based on gRPC messages. The kotlin code complements
+	// the generated java code and does not replace it.
+	KtArg = "--experimental_allow_proto3_optional --kotlin_out=%s --proto_path=%s %s"
+	// MsgArg is the specific argument string required in order to generate
+	// java classes based on gRPC messages.
+	MsgArg = "--experimental_allow_proto3_optional --java_out=%s --proto_path=%s %s"
+	// SvcArg is the specific argument string required in order to generate
+	// java stubs based on gRPC services. Note that the respective protoc
+	// plugin is shipped with grpc-java and has to be available as
+	// protoc-gen-grpc-java.
+	SvcArg = "--experimental_allow_proto3_optional --grpc-java_out=%s --proto_path=%s %s"
+	// Stage is the directory within the destination the rewritten copies of
+	// the schema files are staged in. Note that staged files are only inputs
+	// of the commands and never installed into the destination.
+	Stage = ".pag/proto"
+)
+
+type Config struct {
+	FileSystem afero.Fs
+	Schema     *schema.Schema
+
+	Destination string
+	Exclude     []string
+	// Kotlin defines whether to additionally generate kotlin code.
+	Kotlin bool
+	// Package is the optional prefix of the java packages computed per
+	// schema directory, e.g. "com.example".
+	Package string
+	Source  string
+}
+
+type Java struct {
+	fileSystem afero.Fs
+	schema     *schema.Schema
+
+	destination string
+	kotlin      bool
+	pkg         string
+	source      string
+}
+
+func New(config Config) (*Java, error) {
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
+
+	if config.Destination == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Destination must not be empty", config)
+	}
+	if config.Source == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Source must not be empty", config)
+	}
+
+	var err error
+
+	s := config.Schema
+	if s == nil {
+		c := schema.Config{
+			FileSystem: config.FileSystem,
+
+			Exclude: config.Exclude,
+			Source:  config.Source,
+		}
+
+		s, err = schema.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	j := &Java{
+		fileSystem: config.FileSystem,
+		schema:     s,
+
+		destination: config.Destination,
+		kotlin:      config.Kotlin,
+		pkg:         config.Package,
+		source:      config.Source,
+	}
+
+	return j, nil
+}
+
+// Commands returns the protoc commands generating java code. Note that the
+// commands compile the rewritten copies of the schema files returned by
+// Stage. These files have to be written before the commands are executed.
+func (j *Java) Commands() ([]generate.Command, error) {
+	dirs, err := j.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var cmds []generate.Command
+	for _, d := range dirs {
+		var paths []string
+		for _, f := range d.Files {
+			p, err := j.stage(f.Path)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			paths = append(paths, p)
+		}
+
+		c := func(f string) generate.Command {
+			return generate.Command{
+				Binary:    Binary,
+				Arguments: strings.Split(fmt.Sprintf(f, j.destination, filepath.Join(j.destination, Stage), strings.Join(paths, " ")), " "),
+				Directory: j.destination,
+			}
+		}
+
+		cmds = append(cmds, c(MsgArg))
+		cmds = append(cmds, c(SvcArg))
+
+		if j.kotlin {
+			cmds = append(cmds, c(KtArg))
+		}
+	}
+
+	return cmds, nil
+}
+
+// Files returns no additional files, since protoc generates all of the java
+// code on its own.
+func (j *Java) Files() ([]generate.File, error) {
+	return nil, nil
+}
+
+// Stage returns rewritten copies of all schema files. Java requires dedicated
+// packages in order to organize the generated code. The java package is
+// computed per schema directory based on the configured package prefix and
+// the directory path, e.g. "com.example.pbf.user" for "pbf/user". Schema
+// files not defining the java_package option get the computed package
+// appended, so that the original schema files do not have to be changed.
+// All other options, e.g. java_multiple_files, are left as defined in the
+// schema.
+func (j *Java) Stage() ([]generate.File, error) {
+	dirs, err := j.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var l []generate.File
+	for _, d := range dirs {
+		pkg, err := j.javaPackage(d)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		for _, f := range d.Files {
+			b, err := afero.ReadFile(j.fileSystem, f.Path)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			var opts []string
+			if _, ok := f.Option("java_package"); !ok && pkg != "" {
+				opts = append(opts, fmt.Sprintf("option java_package = %q;", pkg))
+			}
+
+			// Options may be defined anywhere on the top level of a proto
+			// file. Appending the computed options to the end of the file
+			// keeps all line numbers intact, which keeps error messages of
+			// protoc meaningful.
+			if len(opts) != 0 {
+				if len(b) != 0 && b[len(b)-1] != '\n' {
+					b = append(b, '\n')
+				}
+
+				b = append(b, []byte("\n// Added by pag in order to generate java code per schema directory.\n")...)
+				b = append(b, []byte(strings.Join(opts, "\n")+"\n")...)
+			}
+
+			p, err := j.stage(f.Path)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			l = append(l, generate.File{Path: p, Bytes: b})
+		}
+	}
+
+	return l, nil
+}
+
+// javaPackage returns the java package computed for the given schema
+// directory. Path elements are converted into valid java identifiers.
+func (j *Java) javaPackage(d schema.Directory) (string, error) {
+	rel, err := filepath.Rel(j.source, d.Path)
+	if err != nil {
+		return "", tracer.Mask(err)
+	}
+
+	var l []string
+	if j.pkg != "" {
+		l = append(l, strings.Split(j.pkg, ".")...)
+	}
+
+	for _, x := range strings.Split(filepath.ToSlash(rel), "/") {
+		if x == "." {
+			continue
+		}
+
+		l = append(l, toIdentifier(x))
+	}
+
+	return strings.Join(l, "."), nil
+}
+
+// stage returns the location of the rewritten copy of the given schema file.
+func (j *Java) stage(p string) (string, error) {
+	rel, err := filepath.Rel(j.source, p)
+	if err != nil {
+		return "", tracer.Mask(err)
+	}
+
+	return filepath.Join(j.destination, Stage, rel), nil
+}
+
+var keywords = map[string]bool{
+	"abstract": true, "assert": true, "boolean": true, "break": true, "byte": true,
+	"case": true, "catch": true, "char": true, "class": true, "const": true,
+	"continue": true, "default": true, "do": true, "double": true, "else": true,
+	"enum": true, "extends": true, "final": true, "finally": true, "float": true,
+	"for": true, "goto": true, "if": true, "implements": true, "import": true,
+	"instanceof": true, "int": true, "interface": true, "long": true, "native": true,
+	"new": true, "package": true, "private": true, "protected": true, "public": true,
+	"return": true, "short": true, "static": true, "strictfp": true, "super": true,
+	"switch": true, "synchronized": true, "this": true, "throw": true, "throws": true,
+	"transient": true, "try": true, "void": true, "volatile": true, "while": true,
+}
+
+// toIdentifier converts the given directory name into a valid java package
+// identifier, e.g. "user_group" for "user-group" and "new_" for "new".
+func toIdentifier(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+
+	n := b.String()
+
+	if n == "" || unicode.IsDigit([]rune(n)[0]) {
+		n = "_" + n
+	}
+	if keywords[n] {
+		n += "_"
+	}
+
+	return n
+}
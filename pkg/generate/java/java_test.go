@@ -0,0 +1,513 @@
+package java
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_Java_Commands tests the protoc command generation. The protoc
+// binary is used to generate language specific code based on a gRPC apischema.
+// The generated protoc commands are executed in order to generate the actual
+// language specific code. The tests here ensure that the command execution with
+// its flags and positional arguments works as expected.
+//
+//	go test ./pkg/generate/java -run Test_Java_Commands -update
+func Test_Java_Commands(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		dst string
+		kt  bool
+		pkg string
+		src string
+	}{
+		// Case 0 ensures that a single proto file in a single directory is
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf")
+				mustCreateFile(fs, "pbf/foo.proto")
+
+				return fs
+			}(),
+			dst: "./src/main/java/",
+			src: ".",
+		},
+		// Case 1 ensures that a single proto file in a single directory is
+		// scanned accordingly. Note that there are additional empty
+		// directories.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf")
+				mustCreateFile(fs, "pbf/foo.proto")
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateDir(fs, "pbf/user")
+
+				return fs
+			}(),
+			dst: "some/other/dir",
+			src: ".",
+		},
+		// Case 2 ensures that multiple proto files in multiple directories are
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				return fs
+			}(),
+			dst: "./src/main/java/",
+			src: ".",
+		},
+		// Case 3 ensures that multiple proto files in multiple directories are
+		// scanned accordingly. Note that the given destination path is slightly
+		// different.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				return fs
+			}(),
+			dst: "java",
+			src: ".",
+		},
+		// Case 4 ensures that multiple proto files in multiple directories are
+		// scanned accordingly. Note that the given destination path is
+		// absolute.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				mustCreateDir(fs, "pbf/more/deeply/nested")
+				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+
+				return fs
+			}(),
+			dst: "/home/runner/tmp/src/main/java/",
+			src: ".",
+		},
+		// Case 5 ensures that only proto files in the source directory are
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				mustCreateDir(fs, "pbf/more/deeply/nested")
+				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+
+				return fs
+			}(),
+			dst: "./src/main/java/",
+			src: "./pbf/user/",
+		},
+		// Case 6 ensures that the configured package prefix is applied, that
+		// explicitly defined java options are respected and that kotlin code
+		// is generated on demand.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateResource(fs, "pbf/post", "api", "create")
+				mustCreateResource(fs, "pbf/user-group", "api", "search")
+				mustCreateProto(fs, "pbf/new/api.proto", "syntax = \"proto3\";\n\noption java_package = \"com.other\";\noption java_multiple_files = false;")
+
+				return fs
+			}(),
+			dst: "./src/main/java/",
+			kt:  true,
+			pkg: "com.example",
+			src: ".",
+		},
+		// Case 7 ensures that the staged copies of the schema files keep
+		// their paths relative to the source, so that imports relative to the
+		// source resolve against the staged copies compiled by protoc.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateResource(fs, "api/user", "api", "search")
+
+				return fs
+			}(),
+			dst: "/home/runner/app/src/main/java",
+			src: "api",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var g generate.Interface
+			{
+				c := Config{
+					FileSystem: tc.fs,
+
+					Destination: tc.dst,
+					Kotlin:      tc.kt,
+					Package:     tc.pkg,
+					Source:      tc.src,
+				}
+
+				g, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := g.Commands()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual string
+			{
+				var s []string
+				for _, c := range l {
+					s = append(s, c.String())
+				}
+
+				sort.Strings(s)
+
+				actual = strings.Join(s, "\n") + "\n"
+			}
+
+			p := filepath.Join("testdata/commands", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, []byte(actual), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, []byte(actual)) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Java_Stage tests the staged file generation. Java needs dedicated
+// packages which are computed per schema directory and added to rewritten
+// copies of the schema files. The tests here ensure that the staged file
+// generation works according to the gRPC api schema.
+//
+//	go test ./pkg/generate/java -run Test_Java_Stage -update
+func Test_Java_Stage(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		dst string
+		kt  bool
+		pkg string
+		src string
+	}{
+		// Case 0 ensures that a single resource with the conventional create,
+		// delete, search and update operations in a single directory is
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateResource(fs, "pbf", "api", "create", "delete", "search", "update")
+
+				return fs
+			}(),
+			dst: "./src/main/java/",
+			src: ".",
+		},
+		// Case 1 ensures that a single resource in a single directory is
+		// scanned accordingly. Note that there are additional empty
+		// directories.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateResource(fs, "pbf", "api", "create", "delete", "search", "update")
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateDir(fs, "pbf/user")
+
+				return fs
+			}(),
+			dst: "some/other/dir",
+			src: ".",
+		},
+		// Case 2 ensures that multiple resources in multiple directories are
+		// scanned accordingly. Note that the user resource does not define any
+		// service.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateResource(fs, "pbf/post", "api", "create")
+				mustCreateResource(fs, "pbf/user", "foo", "bar", "baz")
+
+				return fs
+			}(),
+			dst: "./src/main/java/",
+			src: ".",
+		},
+		// Case 3 ensures that multiple resources in multiple directories are
+		// scanned accordingly. Note that the given destination path is slightly
+		// different.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateResource(fs, "pbf/post", "api", "create")
+				mustCreateResource(fs, "pbf/user", "foo", "bar", "baz")
+
+				return fs
+			}(),
+			dst: "java",
+			src: ".",
+		},
+		// Case 4 ensures that multiple resources in multiple directories are
+		// scanned accordingly. Note that the given destination path is
+		// absolute.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateResource(fs, "pbf/post", "api", "create")
+				mustCreateResource(fs, "pbf/user", "foo", "bar", "baz")
+				mustCreateResource(fs, "pbf/more/deeply/nested", "api", "foo", "bar", "baz")
+
+				return fs
+			}(),
+			dst: "/home/runner/tmp/src/main/java/",
+			src: ".",
+		},
+		// Case 5 ensures that only resources in the source directory are
+		// scanned accordingly. Note that the generated modules are located
+		// relative to the source directory.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateResource(fs, "pbf/post", "api", "create")
+				mustCreateResource(fs, "pbf/user", "api", "foo", "bar", "baz")
+				mustCreateResource(fs, "pbf/more/deeply/nested", "api", "foo", "bar", "baz")
+
+				return fs
+			}(),
+			dst: "./src/main/java/",
+			src: "./pbf/user/",
+		},
+		// Case 6 ensures that resources with only some of the conventional
+		// operations are imported according to the proto files actually
+		// present.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateResource(fs, "pbf/post", "api", "create", "list")
+				mustCreateResource(fs, "pbf/user-group", "api", "search")
+
+				return fs
+			}(),
+			dst: "./src/main/java/",
+			src: ".",
+		},
+		// Case 7 ensures that the configured package prefix is applied, that
+		// explicitly defined java options are respected and that kotlin code
+		// is generated on demand.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateResource(fs, "pbf/post", "api", "create")
+				mustCreateResource(fs, "pbf/user-group", "api", "search")
+				mustCreateProto(fs, "pbf/new/api.proto", "syntax = \"proto3\";\n\noption java_package = \"com.other\";\noption java_multiple_files = false;")
+
+				return fs
+			}(),
+			dst: "./src/main/java/",
+			kt:  true,
+			pkg: "com.example",
+			src: ".",
+		},
+		// Case 8 ensures that java options other than the package are left
+		// as defined in the schema, e.g. java_multiple_files.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/post/api.proto", "syntax = \"proto3\";\n\noption java_multiple_files = true;\n\nmessage Post {}\n")
+				mustCreateProto(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\nmessage User {}\n")
+
+				return fs
+			}(),
+			dst: "./src/main/java/",
+			src: ".",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var g generate.Stager
+			{
+				c := Config{
+					FileSystem: tc.fs,
+
+					Destination: tc.dst,
+					Kotlin:      tc.kt,
+					Package:     tc.pkg,
+					Source:      tc.src,
+				}
+
+				g, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := g.Stage()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual string
+			{
+				var s []string
+				for _, f := range l {
+					s = append(s, string(f.Bytes))
+					s = append(s, f.Path)
+				}
+
+				actual = strings.Join(s, "\n") + "\n"
+			}
+
+			p := filepath.Join("testdata/stage", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, []byte(actual), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, []byte(actual)) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
+
+func mustCreateDir(fs afero.Fs, p string) {
+	err := fs.MkdirAll(p, 0755)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustCreateFile(fs afero.Fs, p string) {
+	err := afero.WriteFile(fs, p, nil, 0644)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustCreateProto(fs afero.Fs, p string, s string) {
+	err := afero.WriteFile(fs, p, []byte(s), 0644)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// mustCreateResource creates conventional proto files for the given resource
+// directory. The proto file "api" defines the resource's service with one rpc
+// per additional file. Every additional file defines the input and output
+// messages of its operation, e.g. CreateI and CreateO for "create".
+func mustCreateResource(fs afero.Fs, d string, files ...string) {
+	var rpcs []string
+	for _, f := range files {
+		if f == "api" {
+			continue
+		}
+
+		n := strings.Title(f)
+
+		rpcs = append(rpcs, fmt.Sprintf("  rpc %s(%sI) returns (%sO);", n, n, n))
+		mustCreateProto(fs, filepath.Join(d, f+".proto"), fmt.Sprintf("syntax = \"proto3\";\n\nmessage %sI {}\nmessage %sO {}\n", n, n))
+	}
+
+	for _, f := range files {
+		if f == "api" {
+			mustCreateProto(fs, filepath.Join(d, f+".proto"), fmt.Sprintf("syntax = \"proto3\";\n\nservice API {\n%s\n}\n", strings.Join(rpcs, "\n")))
+		}
+	}
+}
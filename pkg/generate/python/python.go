@@ -0,0 +1,185 @@
+package python
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+	"github.com/xh3b4sd/pag/pkg/schema"
+)
+
+const (
+	Binary = "protoc"
+	// MsgArg is the specific argument string required in order to generate
+	// python classes based on gRPC messages.
+	MsgArg = "--experimental_allow_proto3_optional --python_out=%s --proto_path=%s %s"
+	// PyiArg is the specific argument string required in order to generate
+	// python type stubs based on gRPC messages so that editors and type
+	// checkers understand the dynamically created message classes.
+	PyiArg = "--experimental_allow_proto3_optional --pyi_out=%s --proto_path=%s %s"
+	// SvcArg is the specific argument string required in order to generate
+	// python stubs and servicers based on gRPC services. Note that the
+	// respective protoc plugin is shipped with the grpcio-tools package and
+	// has to be available as protoc-gen-grpc_python.
+	SvcArg = "--experimental_allow_proto3_optional --grpc_python_out=%s --proto_path=%s %s"
+)
+
+type Config struct {
+	FileSystem afero.Fs
+	Schema     *schema.Schema
+
+	Destination string
+	Exclude     []string
+	Source      string
+}
+
+type Python struct {
+	schema *schema.Schema
+
+	destination string
+	source      string
+}
+
+func New(config Config) (*Python, error) {
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
+
+	if config.Destination == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Destination must not be empty", config)
+	}
+	if config.Source == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Source must not be empty", config)
+	}
+
+	var err error
+
+	s := config.Schema
+	if s == nil {
+		c := schema.Config{
+			FileSystem: config.FileSystem,
+
+			Exclude: config.Exclude,
+			Source:  config.Source,
+		}
+
+		s, err = schema.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	p := &Python{
+		schema: s,
+
+		destination: config.Destination,
+		source:      config.Source,
+	}
+
+	return p, nil
+}
+
+func (p *Python) Commands() ([]generate.Command, error) {
+	dirs, err := p.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var cmds []generate.Command
+	for _, d := range dirs {
+		c := func(f string) generate.Command {
+			return generate.Command{
+				Binary:    Binary,
+				Arguments: strings.Split(fmt.Sprintf(f, p.destination, p.source, strings.Join(d.Paths(), " ")), " "),
+				Directory: p.destination,
+			}
+		}
+
+		cmds = append(cmds, c(MsgArg))
+		cmds = append(cmds, c(PyiArg))
+		cmds = append(cmds, c(SvcArg))
+	}
+
+	return cmds, nil
+}
+
+// Files returns an "__init__.py" file for the destination directory and every
+// directory below it containing generated code, so that the generated code can
+// be imported as regular python package, e.g. "import pbf.user". The package
+// files of resource directories import the generated modules of the resource.
+func (p *Python) Files() ([]generate.File, error) {
+	dirs, err := p.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	pkgs := map[string][]string{}
+	for _, d := range dirs {
+		rel, err := filepath.Rel(p.source, d.Path)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		var mods []string
+		for _, f := range d.Files {
+			mods = append(mods, f.Base()+"_pb2")
+			if len(f.Services) != 0 {
+				mods = append(mods, f.Base()+"_pb2_grpc")
+			}
+		}
+
+		sort.Strings(mods)
+
+		pkgs[filepath.Join(p.destination, rel)] = mods
+
+		// All parent directories up to the destination have to be packages as
+		// well.
+		for rel != "." {
+			rel = filepath.Dir(rel)
+
+			if _, ok := pkgs[filepath.Join(p.destination, rel)]; !ok {
+				pkgs[filepath.Join(p.destination, rel)] = nil
+			}
+		}
+	}
+
+	var l []generate.File
+	for d, mods := range pkgs {
+		f := generate.File{
+			Path: filepath.Join(d, "__init__.py"),
+		}
+
+		f.Bytes, err = p.render(f.Path, initTemplate, mods)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		l = append(l, f)
+	}
+
+	sort.Slice(l, func(i, j int) bool { return l[i].Path < l[j].Path })
+
+	return l, nil
+}
+
+func (p *Python) render(path string, tmpl string, data interface{}) ([]byte, error) {
+	s, err := template.New(path).Parse(tmpl)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var b bytes.Buffer
+	err = s.ExecuteTemplate(&b, path, data)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,402 @@
+package python
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_Python_Commands tests the protoc command generation. The protoc
+// binary is used to generate language specific code based on a gRPC apischema.
+// The generated protoc commands are executed in order to generate the actual
+// language specific code. The tests here ensure that the command execution with
+// its flags and positional arguments works as expected.
+//
+//	go test ./pkg/generate/python -run Test_Python_Commands -update
+func Test_Python_Commands(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		dst string
+		src string
+	}{
+		// Case 0 ensures that a single proto file in a single directory is
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf")
+				mustCreateFile(fs, "pbf/foo.proto")
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: ".",
+		},
+		// Case 1 ensures that a single proto file in a single directory is
+		// scanned accordingly. Note that there are additional empty
+		// directories.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf")
+				mustCreateFile(fs, "pbf/foo.proto")
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateDir(fs, "pbf/user")
+
+				return fs
+			}(),
+			dst: "some/other/dir",
+			src: ".",
+		},
+		// Case 2 ensures that multiple proto files in multiple directories are
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: ".",
+		},
+		// Case 3 ensures that multiple proto files in multiple directories are
+		// scanned accordingly. Note that the given destination path is slightly
+		// different.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				return fs
+			}(),
+			dst: "gen",
+			src: ".",
+		},
+		// Case 4 ensures that multiple proto files in multiple directories are
+		// scanned accordingly. Note that the given destination path is
+		// absolute.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				mustCreateDir(fs, "pbf/more/deeply/nested")
+				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+
+				return fs
+			}(),
+			dst: "/home/runner/tmp/gen/",
+			src: ".",
+		},
+		// Case 5 ensures that only proto files in the source directory are
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				mustCreateDir(fs, "pbf/more/deeply/nested")
+				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: "./pbf/user/",
+		},
+		// Case 6 ensures that the message, type stub and grpc_python outputs
+		// all point to the destination root, while the source is the proto
+		// path, so that the generated modules are importable relative to the
+		// source, e.g. "user.api_pb2" for "api/user/api.proto".
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "api/root.proto", "syntax = \"proto3\";\n\nmessage Root {}\n")
+				mustCreateProto(fs, "api/user/api.proto", "syntax = \"proto3\";\n\nservice API {}\n")
+
+				return fs
+			}(),
+			dst: "gen/py",
+			src: "api",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var g generate.Interface
+			{
+				c := Config{
+					FileSystem: tc.fs,
+
+					Destination: tc.dst,
+					Source:      tc.src,
+				}
+
+				g, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := g.Commands()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual string
+			{
+				var s []string
+				for _, c := range l {
+					s = append(s, c.String())
+				}
+
+				sort.Strings(s)
+
+				actual = strings.Join(s, "\n") + "\n"
+			}
+
+			p := filepath.Join("testdata/commands", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, []byte(actual), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, []byte(actual)) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Python_Files tests the additional file generation. Python needs package
+// files so that the generated code can be properly imported. The tests here
+// ensure that the additional file generation works according to the gRPC api
+// schema.
+//
+//	go test ./pkg/generate/python -run Test_Python_Files -update
+func Test_Python_Files(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		dst string
+		src string
+	}{
+		// Case 0 ensures that all parent directories of a deeply nested
+		// resource directory become empty packages, so that the generated
+		// modules can be imported via their full package path.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/more/deeply/nested/api.proto", "syntax = \"proto3\";\n\nservice API {\n  rpc Search(SearchI) returns (SearchO);\n}\n")
+				mustCreateProto(fs, "pbf/more/deeply/nested/search.proto", "syntax = \"proto3\";\n\nmessage SearchI {}\nmessage SearchO {}\n")
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: ".",
+		},
+		// Case 1 ensures that grpc modules are only imported for proto files
+		// defining services, independent of the file name. Note that the api
+		// file here does not define any service while the admin file does.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/user/admin.proto", "syntax = \"proto3\";\n\nservice Admin {\n  rpc Delete(DeleteI) returns (DeleteO);\n}\n\nmessage DeleteI {}\nmessage DeleteO {}\n")
+				mustCreateProto(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\nmessage SearchI {}\nmessage SearchO {}\n")
+				mustCreateProto(fs, "pbf/user/user.proto", "syntax = \"proto3\";\n\nmessage User {}\n")
+
+				return fs
+			}(),
+			dst: "gen",
+			src: ".",
+		},
+		// Case 2 ensures that a proto file defining multiple services results
+		// in a single grpc module, since protoc generates all stubs and
+		// servicers of a proto file into the same module.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/post/api.proto", "syntax = \"proto3\";\n\nservice Reader {\n  rpc Search(SearchI) returns (SearchO);\n}\n\nservice Writer {\n  rpc Create(CreateI) returns (CreateO);\n}\n\nmessage CreateI {}\nmessage CreateO {}\nmessage SearchI {}\nmessage SearchO {}\n")
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: ".",
+		},
+		// Case 3 ensures that a resource directory being the parent of another
+		// resource directory keeps importing its own modules instead of being
+		// treated as empty parent package. Note that sibling resources share
+		// their parent packages.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/common.proto", "syntax = \"proto3\";\n\nmessage Page {}\n")
+				mustCreateProto(fs, "pbf/post/post.proto", "syntax = \"proto3\";\n\nmessage Post {}\n")
+				mustCreateProto(fs, "pbf/user/user.proto", "syntax = \"proto3\";\n\nmessage User {}\n")
+
+				return fs
+			}(),
+			dst: "/home/runner/tmp/gen/",
+			src: ".",
+		},
+		// Case 4 ensures that the destination directory itself becomes the
+		// resource package if the source directory is the resource directory.
+		// Note that no parent packages are generated in this case.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/post/post.proto", "syntax = \"proto3\";\n\nmessage Post {}\n")
+				mustCreateProto(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\nservice API {\n  rpc Search(SearchI) returns (SearchO);\n}\n")
+				mustCreateProto(fs, "pbf/user/search.proto", "syntax = \"proto3\";\n\nmessage SearchI {}\nmessage SearchO {}\n")
+
+				return fs
+			}(),
+			dst: "./gen/",
+			src: "./pbf/user/",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var g generate.Interface
+			{
+				c := Config{
+					FileSystem: tc.fs,
+
+					Destination: tc.dst,
+					Source:      tc.src,
+				}
+
+				g, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := g.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual string
+			{
+				var s []string
+				for _, f := range l {
+					s = append(s, string(f.Bytes))
+					s = append(s, f.Path)
+				}
+
+				actual = strings.Join(s, "\n") + "\n"
+			}
+
+			p := filepath.Join("testdata/files", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, []byte(actual), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, []byte(actual)) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
+
+func mustCreateDir(fs afero.Fs, p string) {
+	err := fs.MkdirAll(p, 0755)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustCreateFile(fs afero.Fs, p string) {
+	err := afero.WriteFile(fs, p, nil, 0644)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustCreateProto(fs afero.Fs, p string, s string) {
+	err := afero.WriteFile(fs, p, []byte(s), 0644)
+	if err != nil {
+		panic(err)
+	}
+}
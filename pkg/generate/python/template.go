@@ -0,0 +1,17 @@
+package python
+
+const initTemplate = `#
+# Do not edit. This file was generated via the "pag" command line tool. More
+# information about the tool can be found at github.com/xh3b4sd/pag.
+#
+#     pag generate python
+#
+{{- if . }}
+
+from . import (
+{{- range $m := . }}
+    {{ $m }},
+{{- end }}
+)
+{{- end }}
+`
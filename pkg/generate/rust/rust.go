@@ -0,0 +1,401 @@
+package rust
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+	"github.com/xh3b4sd/pag/pkg/schema"
+)
+
+const (
+	Binary = "protoc"
+	// MsgArg is the specific argument string required in order to generate
+	// rust structs based on gRPC messages using prost. The protoc plugin
+	// protoc-gen-prost writes one file per protocol buffer package, e.g.
+	// "user.rs" for the package "user".
+	MsgArg = "--experimental_allow_proto3_optional --prost_out=%s/ --proto_path=%s %s"
+	// SvcArg is the specific argument string required in order to generate
+	// rust clients and servers based on gRPC services using tonic. The protoc
+	// plugin protoc-gen-tonic writes one file per protocol buffer package,
+	// e.g. "user.tonic.rs" for the package "user". The generated code is
+	// included by the generated module files instead of being appended to the
+	// prost output.
+	SvcArg = "--experimental_allow_proto3_optional --tonic_out=%s/ --tonic_opt=no_include --proto_path=%s %s"
+)
+
+type Config struct {
+	FileSystem afero.Fs
+	Schema     *schema.Schema
+
+	Destination string
+	Exclude     []string
+	Source      string
+}
+
+type Rust struct {
+	schema *schema.Schema
+
+	destination string
+	source      string
+}
+
+func New(config Config) (*Rust, error) {
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
+
+	if config.Destination == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Destination must not be empty", config)
+	}
+	if config.Source == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Source must not be empty", config)
+	}
+
+	var err error
+
+	s := config.Schema
+	if s == nil {
+		c := schema.Config{
+			FileSystem: config.FileSystem,
+
+			Exclude: config.Exclude,
+			Source:  config.Source,
+		}
+
+		s, err = schema.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	r := &Rust{
+		schema: s,
+
+		destination: config.Destination,
+		source:      config.Source,
+	}
+
+	return r, nil
+}
+
+// Commands returns the protoc commands generating prost messages and tonic
+// services. Every resource directory is generated into its own directory
+// within the destination, since the generated files are named after their
+// protocol buffer packages and would otherwise collide.
+func (r *Rust) Commands() ([]generate.Command, error) {
+	dirs, err := r.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var cmds []generate.Command
+	for _, d := range dirs {
+		p, err := r.path(d)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		c := func(f string) generate.Command {
+			return generate.Command{
+				Binary:    Binary,
+				Arguments: strings.Split(fmt.Sprintf(f, p, r.source, strings.Join(d.Paths(), " ")), " "),
+				Directory: p,
+			}
+		}
+
+		cmds = append(cmds, c(MsgArg))
+		cmds = append(cmds, c(SvcArg))
+	}
+
+	return cmds, nil
+}
+
+// Files returns the module tree of the generated code. The destination gets a
+// "lib.rs" file and every directory below it gets a "mod.rs" file, so that
+// the module tree mirrors the directory layout of the gRPC api schema, e.g.
+// "pbf::user". Every resource module includes the generated prost and tonic
+// code and provides the same conventions applied by the typescript index.ts
+// file. The input and output messages of create.proto can be accessed via
+// "pbf::user::create::I" and "pbf::user::create::O", while the client of the
+// resource's service can be accessed via "pbf::user::Client". Note that
+// prost resolves references to other packages relative to the package
+// hierarchy, which is why package names should follow the directory layout.
+func (r *Rust) Files() ([]generate.File, error) {
+	dirs, err := r.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	type Member struct {
+		Key  string
+		Name string
+	}
+
+	type Group struct {
+		Module  string
+		Members []Member
+	}
+
+	type Service struct {
+		Key    string
+		Module string
+		Name   string
+	}
+
+	type Module struct {
+		Groups   []Group
+		Includes []string
+		Modules  []string
+		Services []Service
+	}
+
+	mods := map[string]*Module{}
+	ensure := func(p string) *Module {
+		m, ok := mods[p]
+		if !ok {
+			m = &Module{}
+			mods[p] = m
+		}
+
+		return m
+	}
+
+	for _, d := range dirs {
+		p, err := r.path(d)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		m := ensure(p)
+
+		pkgs := map[string]bool{}
+		svcs := map[string]bool{}
+		for _, f := range d.Files {
+			pkgs[f.Package] = true
+			if len(f.Services) != 0 {
+				svcs[f.Package] = true
+			}
+
+			for _, s := range f.Services {
+				x := Service{
+					Module: toSnake(s.Name),
+					Name:   toCamel(s.Name),
+				}
+
+				if len(d.Services()) > 1 {
+					x.Key = toCamel(s.Name)
+				}
+
+				m.Services = append(m.Services, x)
+			}
+
+			if len(f.Messages) == 0 && len(f.Enums) == 0 {
+				continue
+			}
+
+			g := Group{
+				Module: toSnake(f.Base()),
+			}
+
+			var names []string
+			for _, x := range f.Messages {
+				names = append(names, x.Name)
+			}
+			for _, x := range f.Enums {
+				names = append(names, x.Name)
+			}
+
+			for _, x := range names {
+				k := toCamel(f.Base())
+				n := toCamel(x)
+
+				y := Member{
+					Key:  n,
+					Name: n,
+				}
+
+				if strings.HasPrefix(n, k) && len(n) > len(k) {
+					y.Key = strings.TrimPrefix(n, k)
+				}
+
+				g.Members = append(g.Members, y)
+			}
+
+			m.Groups = append(m.Groups, g)
+		}
+
+		for k := range pkgs {
+			m.Includes = append(m.Includes, toFile(k)+".rs")
+		}
+		for k := range svcs {
+			m.Includes = append(m.Includes, toFile(k)+".tonic.rs")
+		}
+
+		sort.Strings(m.Includes)
+
+		// All parent directories up to the destination have to declare their
+		// child modules.
+		for p != filepath.Clean(r.destination) {
+			c := toSnake(filepath.Base(p))
+			p = filepath.Dir(p)
+			ensure(p).Modules = append(ensure(p).Modules, c)
+		}
+	}
+
+	var l []generate.File
+	for p, m := range mods {
+		m.Modules = unique(m.Modules)
+
+		f := generate.File{
+			Path: filepath.Join(p, "mod.rs"),
+		}
+
+		if p == filepath.Clean(r.destination) {
+			f.Path = filepath.Join(p, "lib.rs")
+		}
+
+		f.Bytes, err = r.render(f.Path, moduleTemplate, m)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		l = append(l, f)
+	}
+
+	sort.Slice(l, func(i, j int) bool { return l[i].Path < l[j].Path })
+
+	return l, nil
+}
+
+// path returns the directory within the destination the given resource
+// directory is generated into.
+func (r *Rust) path(d schema.Directory) (string, error) {
+	rel, err := filepath.Rel(r.source, d.Path)
+	if err != nil {
+		return "", tracer.Mask(err)
+	}
+
+	var l []string
+	for _, x := range strings.Split(rel, string(filepath.Separator)) {
+		if x == "." {
+			continue
+		}
+
+		l = append(l, toSnake(x))
+	}
+
+	return filepath.Join(append([]string{r.destination}, l...)...), nil
+}
+
+func (r *Rust) render(path string, tmpl string, data interface{}) ([]byte, error) {
+	s, err := template.New(path).Parse(tmpl)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var b bytes.Buffer
+	err = s.ExecuteTemplate(&b, path, data)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// toCamel converts the given identifier into upper camel case the same way
+// prost and tonic name their generated types, e.g. "Api" for "API" and
+// "CreateIObj" for "CreateI_Obj".
+func toCamel(s string) string {
+	var n string
+	for _, w := range words(s) {
+		n += strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+	}
+
+	return n
+}
+
+// toFile returns the file name prost and tonic use for the generated code of
+// the given protocol buffer package. Files without package are written to
+// "_.rs".
+func toFile(pkg string) string {
+	if pkg == "" {
+		return "_"
+	}
+
+	return pkg
+}
+
+// toSnake converts the given identifier into snake case the same way prost and
+// tonic name their generated modules, e.g. "api" for "API" and "user_group"
+// for "user-group".
+func toSnake(s string) string {
+	var l []string
+	for _, w := range words(s) {
+		l = append(l, strings.ToLower(w))
+	}
+
+	return strings.Join(l, "_")
+}
+
+func unique(l []string) []string {
+	m := map[string]bool{}
+
+	var u []string
+	for _, x := range l {
+		if m[x] {
+			continue
+		}
+
+		m[x] = true
+		u = append(u, x)
+	}
+
+	sort.Strings(u)
+
+	return u
+}
+
+// words splits the given identifier into its words. Words are separated by
+// non alphanumeric characters and by changes in letter case, where a sequence
+// of upper case letters forms a single word, e.g. "HTTPServer" results in
+// "HTTP" and "Server".
+func words(s string) []string {
+	r := []rune(s)
+
+	var l []string
+	var w []rune
+	for i, c := range r {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			if len(w) != 0 {
+				l = append(l, string(w))
+				w = nil
+			}
+			continue
+		}
+
+		if len(w) != 0 && unicode.IsUpper(c) {
+			p := r[i-1]
+			if unicode.IsLower(p) || unicode.IsDigit(p) || (unicode.IsUpper(p) && i+1 < len(r) && unicode.IsLower(r[i+1])) {
+				l = append(l, string(w))
+				w = nil
+			}
+		}
+
+		w = append(w, c)
+	}
+
+	if len(w) != 0 {
+		l = append(l, string(w))
+	}
+
+	return l
+}
@@ -0,0 +1,465 @@
+package rust
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_Rust_Commands tests the protoc command generation. The protoc
+// binary is used to generate language specific code based on a gRPC apischema.
+// The generated protoc commands are executed in order to generate the actual
+// language specific code. The tests here ensure that the command execution with
+// its flags and positional arguments works as expected.
+//
+//	go test ./pkg/generate/rust -run Test_Rust_Commands -update
+func Test_Rust_Commands(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		dst string
+		src string
+	}{
+		// Case 0 ensures that a single proto file in a single directory is
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf")
+				mustCreateFile(fs, "pbf/foo.proto")
+
+				return fs
+			}(),
+			dst: "./src/",
+			src: ".",
+		},
+		// Case 1 ensures that a single proto file in a single directory is
+		// scanned accordingly. Note that there are additional empty
+		// directories.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf")
+				mustCreateFile(fs, "pbf/foo.proto")
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateDir(fs, "pbf/user")
+
+				return fs
+			}(),
+			dst: "some/other/dir",
+			src: ".",
+		},
+		// Case 2 ensures that multiple proto files in multiple directories are
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				return fs
+			}(),
+			dst: "./src/",
+			src: ".",
+		},
+		// Case 3 ensures that multiple proto files in multiple directories are
+		// scanned accordingly. Note that the given destination path is slightly
+		// different.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				return fs
+			}(),
+			dst: "src",
+			src: ".",
+		},
+		// Case 4 ensures that multiple proto files in multiple directories are
+		// scanned accordingly. Note that the given destination path is
+		// absolute.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				mustCreateDir(fs, "pbf/more/deeply/nested")
+				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+
+				return fs
+			}(),
+			dst: "/home/runner/tmp/src/",
+			src: ".",
+		},
+		// Case 5 ensures that only proto files in the source directory are
+		// scanned accordingly.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateDir(fs, "pbf/post")
+				mustCreateFile(fs, "pbf/post/api.proto")
+				mustCreateFile(fs, "pbf/post/create.proto")
+
+				mustCreateDir(fs, "pbf/user")
+				mustCreateFile(fs, "pbf/user/foo.proto")
+				mustCreateFile(fs, "pbf/user/bar.proto")
+				mustCreateFile(fs, "pbf/user/baz.proto")
+
+				mustCreateDir(fs, "pbf/more/deeply/nested")
+				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
+				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+
+				return fs
+			}(),
+			dst: "./src/",
+			src: "./pbf/user/",
+		},
+		// Case 6 ensures that every resource directory is generated into its
+		// own module directory named after the rust module, e.g.
+		// "src/pbf/user_group/" for "pbf/user-group", and that tonic is told
+		// not to include its output into the prost output.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/user-group/api.proto", "syntax = \"proto3\";\n\nservice API {}\n")
+				mustCreateProto(fs, "pbf/user-group/search.proto", "syntax = \"proto3\";\n\nmessage SearchI {}\n")
+
+				return fs
+			}(),
+			dst: "src",
+			src: ".",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var g generate.Interface
+			{
+				c := Config{
+					FileSystem: tc.fs,
+
+					Destination: tc.dst,
+					Source:      tc.src,
+				}
+
+				g, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := g.Commands()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual string
+			{
+				var s []string
+				for _, c := range l {
+					s = append(s, c.String())
+				}
+
+				sort.Strings(s)
+
+				actual = strings.Join(s, "\n") + "\n"
+			}
+
+			p := filepath.Join("testdata/commands", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, []byte(actual), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, []byte(actual)) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Rust_Files tests the additional file generation. Rust needs module files
+// so that the generated code can be properly used as crate. The tests here
+// ensure that the additional file generation works according to the gRPC api
+// schema.
+//
+//	go test ./pkg/generate/rust -run Test_Rust_Files -update
+func Test_Rust_Files(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		dst string
+		src string
+	}{
+		// Case 0 ensures that the generated code is included via the file
+		// names prost and tonic derive from the protocol buffer package. Note
+		// that the post resource does not declare any package and does not
+		// define any service.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/post/post.proto", "syntax = \"proto3\";\n\nmessage Post {}\n")
+				mustCreateProto(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\npackage pbf.user;\n\nservice API {\n  rpc Create(CreateI) returns (CreateO);\n}\n")
+				mustCreateProto(fs, "pbf/user/create.proto", "syntax = \"proto3\";\n\npackage pbf.user;\n\nmessage CreateI {}\nmessage CreateO {}\n")
+
+				return fs
+			}(),
+			dst: "./src/",
+			src: ".",
+		},
+		// Case 1 ensures that directory names are converted into valid rust
+		// module names, both for the module files of the resources and for the
+		// module declarations of their parents.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/HTTPServer/config.proto", "syntax = \"proto3\";\n\npackage pbf.http_server;\n\nmessage Config {}\n")
+				mustCreateProto(fs, "pbf/user-group/search.proto", "syntax = \"proto3\";\n\npackage pbf.user_group;\n\nmessage SearchI {}\nmessage SearchO {}\n")
+
+				return fs
+			}(),
+			dst: "src",
+			src: ".",
+		},
+		// Case 2 ensures that the clients and servers of multiple services
+		// within the same resource are keyed by their service names, while
+		// a single service is exported as plain Client and Server.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/post/api.proto", "syntax = \"proto3\";\n\npackage pbf.post;\n\nservice Reader {\n  rpc Search(SearchI) returns (SearchO);\n}\n\nservice HTTPWriter {\n  rpc Create(CreateI) returns (CreateO);\n}\n\nmessage CreateI {}\nmessage CreateO {}\nmessage SearchI {}\nmessage SearchO {}\n")
+				mustCreateProto(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\npackage pbf.user;\n\nservice API {\n  rpc Search(SearchI) returns (SearchO);\n}\n\nmessage SearchI {}\nmessage SearchO {}\n")
+
+				return fs
+			}(),
+			dst: "/home/runner/tmp/src/",
+			src: ".",
+		},
+		// Case 3 ensures that the members of a file module are keyed without
+		// the prefix of the camel cased file name, while members not sharing
+		// the prefix keep their names.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/user/user_group.proto", "syntax = \"proto3\";\n\npackage pbf.user;\n\nmessage UserGroupI {}\nmessage UserGroupO {}\nmessage UserGroup {}\nmessage UserGroupI_Obj {}\n\nenum Role {\n  ROLE_UNSPECIFIED = 0;\n}\n")
+
+				return fs
+			}(),
+			dst: "./src/",
+			src: ".",
+		},
+		// Case 4 ensures that the crate root includes the generated code
+		// directly if the source directory is the resource directory.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateProto(fs, "pbf/post/post.proto", "syntax = \"proto3\";\n\npackage pbf.post;\n\nmessage Post {}\n")
+				mustCreateProto(fs, "pbf/user/api.proto", "syntax = \"proto3\";\n\npackage pbf.user;\n\nservice API {\n  rpc Search(SearchI) returns (SearchO);\n}\n")
+				mustCreateProto(fs, "pbf/user/search.proto", "syntax = \"proto3\";\n\npackage pbf.user;\n\nmessage SearchI {}\nmessage SearchO {}\n")
+
+				return fs
+			}(),
+			dst: "./src/",
+			src: "./pbf/user/",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var g generate.Interface
+			{
+				c := Config{
+					FileSystem: tc.fs,
+
+					Destination: tc.dst,
+					Source:      tc.src,
+				}
+
+				g, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := g.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual string
+			{
+				var s []string
+				for _, f := range l {
+					s = append(s, string(f.Bytes))
+					s = append(s, f.Path)
+				}
+
+				actual = strings.Join(s, "\n") + "\n"
+			}
+
+			p := filepath.Join("testdata/files", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, []byte(actual), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, []byte(actual)) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Rust_toSnake tests the conversion of identifiers into the module names
+// prost and tonic generate. The words of an identifier are separated by non
+// alphanumeric characters and by changes in letter case, where a sequence of
+// upper case letters forms a single word.
+func Test_Rust_toSnake(t *testing.T) {
+	testCases := []struct {
+		s     string
+		camel string
+		snake string
+	}{
+		// Case 0 ensures that lower case identifiers are kept.
+		{
+			s:     "user",
+			camel: "User",
+			snake: "user",
+		},
+		// Case 1 ensures that upper case identifiers form a single word.
+		{
+			s:     "API",
+			camel: "Api",
+			snake: "api",
+		},
+		// Case 2 ensures that dashes separate words.
+		{
+			s:     "user-group",
+			camel: "UserGroup",
+			snake: "user_group",
+		},
+		// Case 3 ensures that a sequence of upper case letters is separated
+		// from the following word.
+		{
+			s:     "HTTPServer",
+			camel: "HttpServer",
+			snake: "http_server",
+		},
+		// Case 4 ensures that underscores separate words while upper case
+		// letters following lower case letters or digits start new words.
+		{
+			s:     "CreateI_Obj",
+			camel: "CreateIObj",
+			snake: "create_i_obj",
+		},
+		// Case 5 ensures that digits are kept within their words.
+		{
+			s:     "v1Beta2",
+			camel: "V1Beta2",
+			snake: "v1_beta2",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			camel := toCamel(tc.s)
+			if camel != tc.camel {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.camel, camel))
+			}
+
+			snake := toSnake(tc.s)
+			if snake != tc.snake {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.snake, snake))
+			}
+		})
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
+
+func mustCreateDir(fs afero.Fs, p string) {
+	err := fs.MkdirAll(p, 0755)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustCreateFile(fs afero.Fs, p string) {
+	err := afero.WriteFile(fs, p, nil, 0644)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustCreateProto(fs afero.Fs, p string, s string) {
+	err := afero.WriteFile(fs, p, []byte(s), 0644)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,33 @@
+package rust
+
+const moduleTemplate = `//
+// Do not edit. This file was generated via the "pag" command line tool. More
+// information about the tool can be found at github.com/xh3b4sd/pag.
+//
+//     pag generate rust
+//
+{{- if .Modules }}
+{{ range $m := .Modules }}
+pub mod {{ $m }};
+{{- end }}
+{{- end }}
+{{- if .Includes }}
+{{ range $i := .Includes }}
+include!("{{ $i }}");
+{{- end }}
+{{- end }}
+{{- if .Services }}
+{{ range $s := .Services }}
+pub use self::{{ $s.Module }}_client::{{ $s.Name }}Client as {{ $s.Key }}Client;
+pub use self::{{ $s.Module }}_server::{{ $s.Name }}Server as {{ $s.Key }}Server;
+{{- end }}
+{{- end }}
+{{- range $g := .Groups }}
+
+pub mod {{ $g.Module }} {
+{{- range $m := $g.Members }}
+    pub use super::{{ $m.Name }} as {{ $m.Key }};
+{{- end }}
+}
+{{- end }}
+`
@@ -0,0 +1,9 @@
+package generate
+
+// Stager is implemented by generators whose commands read files provided by
+// the generator itself, e.g. rewritten copies of schema files. Staged files
+// are written before the commands are executed, but unlike the files returned
+// by Files, they are never installed into the destination.
+type Stager interface {
+	Stage() ([]File, error)
+}
@@ -0,0 +1,279 @@
+package swift
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+
+	"github.com/xh3b4sd/pag/pkg/generate"
+	"github.com/xh3b4sd/pag/pkg/schema"
+)
+
+const (
+	Binary = "protoc"
+	// MsgArg is the specific argument string required in order to generate
+	// swift structs based on gRPC messages. The generated types are public so
+	// that they can be used from within other swift modules.
+	MsgArg = "--experimental_allow_proto3_optional --swift_out=%s --swift_opt=Visibility=Public --proto_path=%s %s"
+	// SvcArg is the specific argument string required in order to generate
+	// swift clients based on gRPC services. iOS applications only ever call
+	// gRPC services, which is why no server code is generated.
+	SvcArg = "--experimental_allow_proto3_optional --grpc-swift_out=%s --grpc-swift_opt=Visibility=Public,Client=true,Server=false --proto_path=%s %s"
+)
+
+type Config struct {
+	FileSystem afero.Fs
+	Schema     *schema.Schema
+
+	Destination string
+	Exclude     []string
+	Source      string
+}
+
+type Swift struct {
+	schema *schema.Schema
+
+	destination string
+	source      string
+}
+
+func New(config Config) (*Swift, error) {
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
+
+	if config.Destination == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Destination must not be empty", config)
+	}
+	if config.Source == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Source must not be empty", config)
+	}
+
+	var err error
+
+	s := config.Schema
+	if s == nil {
+		c := schema.Config{
+			FileSystem: config.FileSystem,
+
+			Exclude: config.Exclude,
+			Source:  config.Source,
+		}
+
+		s, err = schema.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	w := &Swift{
+		schema: s,
+
+		destination: config.Destination,
+		source:      config.Source,
+	}
+
+	return w, nil
+}
+
+func (w *Swift) Commands() ([]generate.Command, error) {
+	dirs, err := w.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var cmds []generate.Command
+	for _, d := range dirs {
+		c := func(f string) generate.Command {
+			return generate.Command{
+				Binary:    Binary,
+				Arguments: strings.Split(fmt.Sprintf(f, w.destination, w.source, strings.Join(d.Paths(), " ")), " "),
+				Directory: w.destination,
+			}
+		}
+
+		cmds = append(cmds, c(MsgArg))
+		cmds = append(cmds, c(SvcArg))
+	}
+
+	return cmds, nil
+}
+
+// Files returns one aggregate swift file per resource. The aggregate file
+// namespaces the generated types of the resource the same way the typescript
+// index.ts file does. The generated types are prefixed by their protocol
+// buffer package, e.g. User_CreateI, and can be accessed via User.Create.I,
+// while the generated client of the resource's service can be accessed via
+// User.Client and User.AsyncClient.
+func (w *Swift) Files() ([]generate.File, error) {
+	dirs, err := w.schema.Directories()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	type Member struct {
+		Key  string
+		Name string
+	}
+
+	type Group struct {
+		Key     string
+		Members []Member
+	}
+
+	type Resource struct {
+		Name    string
+		Clients []Member
+		Groups  []Group
+	}
+
+	// Type aliases referring to a type of the same name would refer to
+	// themselves within the namespace. Such types are accessible anyway and
+	// are therefore omitted.
+	alias := func(l []Member, m Member) []Member {
+		if m.Key == m.Name {
+			return l
+		}
+
+		return append(l, m)
+	}
+
+	var l []generate.File
+	for _, d := range dirs {
+		r := Resource{
+			Name: toCamel(d.Resource()),
+		}
+
+		for _, f := range d.Files {
+			for _, s := range f.Services {
+				var k string
+				if len(d.Services()) > 1 {
+					k = s.Name
+				}
+
+				r.Clients = alias(r.Clients, Member{Key: k + "Client", Name: toPrefix(f) + s.Name + "NIOClient"})
+				r.Clients = alias(r.Clients, Member{Key: k + "AsyncClient", Name: toPrefix(f) + s.Name + "AsyncClient"})
+			}
+		}
+
+		for _, f := range d.Files {
+			if len(f.Messages) == 0 && len(f.Enums) == 0 {
+				continue
+			}
+
+			g := Group{
+				Key: toCamel(f.Base()),
+			}
+
+			var names []string
+			for _, m := range f.Messages {
+				names = append(names, m.Name)
+			}
+			for _, e := range f.Enums {
+				names = append(names, e.Name)
+			}
+
+			for _, x := range names {
+				m := Member{
+					Key:  x,
+					Name: toPrefix(f) + x,
+				}
+
+				if strings.HasPrefix(x, g.Key) && len(x) > len(g.Key) {
+					m.Key = strings.TrimPrefix(x, g.Key)
+				}
+
+				g.Members = alias(g.Members, m)
+			}
+
+			r.Groups = append(r.Groups, g)
+		}
+
+		// Resources without any messages or services do not have anything to
+		// namespace and are therefore omitted.
+		if len(r.Clients) == 0 && len(r.Groups) == 0 {
+			continue
+		}
+
+		rel, err := filepath.Rel(w.source, d.Path)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		f := generate.File{
+			Path: filepath.Join(w.destination, rel, r.Name+".swift"),
+		}
+
+		f.Bytes, err = w.render(f.Path, resourceTemplate, r)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		l = append(l, f)
+	}
+
+	return l, nil
+}
+
+func (w *Swift) render(path string, tmpl string, data interface{}) ([]byte, error) {
+	s, err := template.New(path).Parse(tmpl)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	var b bytes.Buffer
+	err = s.ExecuteTemplate(&b, path, data)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// toCamel converts file and directory names into valid swift identifiers,
+// e.g. "list_items" into "ListItems".
+func toCamel(s string) string {
+	f := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+
+	var n string
+	for _, x := range strings.FieldsFunc(s, f) {
+		n += strings.Title(x)
+	}
+
+	return n
+}
+
+// toPrefix returns the prefix protoc-gen-swift uses for the types generated
+// from the given file. The prefix is either defined by the swift_prefix option
+// or derived from the protocol buffer package, e.g. "Pbf_User_" for
+// "pbf.user".
+func toPrefix(f schema.File) string {
+	p, ok := f.Option("swift_prefix")
+	if ok {
+		return p
+	}
+
+	if f.Package == "" {
+		return ""
+	}
+
+	var l []string
+	for _, x := range strings.Split(f.Package, ".") {
+		var n string
+		for _, y := range strings.Split(x, "_") {
+			n += strings.Title(y)
+		}
+
+		l = append(l, n)
+	}
+
+	return strings.Join(l, "_") + "_"
+}
@@ -0,0 +1,23 @@
+package swift
+
+const resourceTemplate = `//
+// Do not edit. This file was generated via the "pag" command line tool. More
+// information about the tool can be found at github.com/xh3b4sd/pag.
+//
+//     pag generate swift
+//
+
+public enum {{ .Name }} {
+{{- range $c := .Clients }}
+    public typealias {{ $c.Key }} = {{ $c.Name }}
+{{- end }}
+{{- range $g := .Groups }}
+
+    public enum {{ $g.Key }} {
+{{- range $m := $g.Members }}
+        public typealias {{ $m.Key }} = {{ $m.Name }}
+{{- end }}
+    }
+{{- end }}
+}
+`
@@ -9,30 +9,20 @@ const indexTemplate = `//
 {{ range $r := . }}
 // -------------------------------------------------------------------------- //
 
-import * as {{ $r.Dir | ToResource }}Client  from "./{{ $r.Dir }}/ApiServiceClientPb";
-import * as {{ $r.Dir | ToResource }}Create  from "./{{ $r.Dir }}/create_pb";
-import * as {{ $r.Dir | ToResource }}Delete  from "./{{ $r.Dir }}/delete_pb";
-import * as {{ $r.Dir | ToResource }}Search  from "./{{ $r.Dir }}/search_pb";
-import * as {{ $r.Dir | ToResource }}Update  from "./{{ $r.Dir }}/update_pb";
-
-export const {{ $r.Dir | ToResource }} = {
-  Client:  {{ $r.Dir | ToResource }}Client.APIClient,
-  Create: {
-    I: {{ $r.Dir | ToResource }}Create.CreateI,
-    O: {{ $r.Dir | ToResource }}Create.CreateO,
-  },
-  Delete: {
-    I: {{ $r.Dir | ToResource }}Delete.DeleteI,
-    O: {{ $r.Dir | ToResource }}Delete.DeleteO,
-  },
-  Search: {
-    I: {{ $r.Dir | ToResource }}Search.SearchI,
-    O: {{ $r.Dir | ToResource }}Search.SearchO,
-  },
-  Update: {
-    I: {{ $r.Dir | ToResource }}Update.UpdateI,
-    O: {{ $r.Dir | ToResource }}Update.UpdateO,
+{{ range $i := $r.Imports -}}
+import * as {{ $i.Alias }}  from "{{ $i.Path }}";
+{{ end }}
+export const {{ $r.Name }} = {
+{{- range $c := $r.Clients }}
+  {{ $c.Key }}:  {{ $c.Alias }}.{{ $c.Name }},
+{{- end }}
+{{- range $g := $r.Groups }}
+  {{ $g.Key }}: {
+{{- range $m := $g.Members }}
+    {{ $m.Key }}: {{ $g.Alias }}.{{ $m.Name }},
+{{- end }}
   },
+{{- end }}
 }
 
 // -------------------------------------------------------------------------- //
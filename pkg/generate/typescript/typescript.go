@@ -3,16 +3,16 @@ package typescript
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/spf13/afero"
 	"github.com/xh3b4sd/tracer"
 
 	"github.com/xh3b4sd/pag/pkg/generate"
+	"github.com/xh3b4sd/pag/pkg/schema"
 )
 
 const (
@@ -32,13 +32,15 @@ const (
 
 type Config struct {
 	FileSystem afero.Fs
+	Schema     *schema.Schema
 
 	Destination string
+	Exclude     []string
 	Source      string
 }
 
 type Typescript struct {
-	fileSystem afero.Fs
+	schema *schema.Schema
 
 	destination string
 	source      string
@@ -56,8 +58,25 @@ func New(config Config) (*Typescript, error) {
 		return nil, tracer.Maskf(invalidConfigError, "%T.Source must not be empty", config)
 	}
 
+	var err error
+
+	s := config.Schema
+	if s == nil {
+		c := schema.Config{
+			FileSystem: config.FileSystem,
+
+			Exclude: config.Exclude,
+			Source:  config.Source,
+		}
+
+		s, err = schema.New(c)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
 	t := &Typescript{
-		fileSystem: config.FileSystem,
+		schema: s,
 
 		destination: config.Destination,
 		source:      config.Source,
@@ -67,17 +86,17 @@ func New(config Config) (*Typescript, error) {
 }
 
 func (t *Typescript) Commands() ([]generate.Command, error) {
-	dirs, err := t.dirs(".proto")
+	dirs, err := t.schema.Directories()
 	if err != nil {
 		return nil, tracer.Mask(err)
 	}
 
 	var cmds []generate.Command
-	for _, l := range dirs {
+	for _, d := range dirs {
 		c := func(f string) generate.Command {
 			return generate.Command{
 				Binary:    Binary,
-				Arguments: strings.Split(fmt.Sprintf(f, t.destination, t.source, strings.Join(l, " ")), " "),
+				Arguments: strings.Split(fmt.Sprintf(f, t.destination, t.source, strings.Join(d.Paths(), " ")), " "),
 				Directory: t.destination,
 			}
 		}
@@ -90,7 +109,7 @@ func (t *Typescript) Commands() ([]generate.Command, error) {
 }
 
 func (t *Typescript) Files() ([]generate.File, error) {
-	d, err := t.dirs(".proto")
+	dirs, err := t.schema.Directories()
 	if err != nil {
 		return nil, tracer.Mask(err)
 	}
@@ -99,7 +118,12 @@ func (t *Typescript) Files() ([]generate.File, error) {
 	{
 		p := filepath.Join(t.destination, "index.ts")
 
-		b, err := t.render(p, indexTemplate, t.data(d))
+		d, err := t.data(dirs)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		b, err := t.render(p, indexTemplate, d)
 		if err != nil {
 			return nil, tracer.Mask(err)
 		}
@@ -115,80 +139,145 @@ func (t *Typescript) Files() ([]generate.File, error) {
 	return l, nil
 }
 
-func (t *Typescript) data(dirs map[string][]string) interface{} {
-	type Data struct {
-		Dir string
+// data computes the template data for the index.ts file based on the parsed
+// schema. Every directory describes a resource. Every proto file defining
+// messages or enums results in an import of the generated "_pb" module, and
+// every proto file defining services results in an import of the generated
+// "ServiceClientPb" module. Messages are grouped by the proto file they are
+// defined in. A message prefixed with the name of its proto file is exported
+// using the remaining suffix, so that e.g. CreateI of create.proto can be
+// accessed via User.Create.I.
+func (t *Typescript) data(dirs []schema.Directory) (interface{}, error) {
+	type Import struct {
+		Alias string
+		Path  string
 	}
 
-	var data []Data
+	type Member struct {
+		Key  string
+		Name string
+	}
 
-	for d := range dirs {
-		data = append(data, Data{Dir: d})
+	type Client struct {
+		Key   string
+		Alias string
+		Name  string
 	}
 
-	sort.Slice(data, func(i, j int) bool { return data[i].Dir < data[j].Dir })
+	type Group struct {
+		Key     string
+		Alias   string
+		Members []Member
+	}
 
-	return data
-}
+	type Resource struct {
+		Name    string
+		Imports []Import
+		Clients []Client
+		Groups  []Group
+	}
 
-func (t *Typescript) dirs(exts ...string) (map[string][]string, error) {
-	dirs := map[string][]string{}
-	{
-		walkFunc := func(p string, i os.FileInfo, err error) error {
-			if err != nil {
-				return tracer.Mask(err)
+	var data []Resource
+
+	for _, d := range dirs {
+		r := Resource{
+			Name: toResource(d.Path),
+		}
+
+		rel, err := filepath.Rel(t.source, d.Path)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		var n int
+		for _, f := range d.Files {
+			if len(f.Services) != 0 {
+				n++
 			}
+		}
 
-			if i.IsDir() && i.Name() == ".git" {
-				return filepath.SkipDir
+		// The generated client modules are imported first so that the client
+		// is the first property of every exported resource.
+		for _, f := range d.Files {
+			if len(f.Services) == 0 {
+				continue
 			}
 
-			if i.IsDir() && i.Name() == ".github" {
-				return filepath.SkipDir
+			i := Import{
+				Alias: r.Name + "Client",
+				Path:  importPath(rel, toUpperFirst(f.Base())+"ServiceClientPb"),
 			}
 
-			// We do not want to track directories. We are interested in
-			// directories containing specific files.
-			if i.IsDir() {
-				return nil
+			if n > 1 {
+				i.Alias = r.Name + toCamel(f.Base()) + "Client"
 			}
 
-			// We do not want to track files with the wrong extension. We are
-			// interested in protocol buffer files having the ".proto"
-			// extension.
-			for _, e := range exts {
-				if filepath.Ext(i.Name()) != e {
-					return nil
+			r.Imports = append(r.Imports, i)
+
+			for _, s := range f.Services {
+				c := Client{
+					Key:   "Client",
+					Alias: i.Alias,
+					Name:  s.Name + "Client",
+				}
+
+				if len(d.Services()) > 1 {
+					c.Key = s.Name + "Client"
 				}
+
+				r.Clients = append(r.Clients, c)
 			}
+		}
 
-			dirs[filepath.Dir(p)] = append(dirs[filepath.Dir(p)], filepath.Join(filepath.Dir(p), i.Name()))
+		for _, f := range d.Files {
+			if len(f.Messages) == 0 && len(f.Enums) == 0 {
+				continue
+			}
 
-			return nil
+			g := Group{
+				Key:   toCamel(f.Base()),
+				Alias: r.Name + toCamel(f.Base()),
+			}
+
+			var names []string
+			for _, m := range f.Messages {
+				names = append(names, m.Name)
+			}
+			for _, e := range f.Enums {
+				names = append(names, e.Name)
+			}
+
+			for _, x := range names {
+				m := Member{
+					Key:  x,
+					Name: x,
+				}
+
+				if strings.HasPrefix(x, g.Key) && len(x) > len(g.Key) {
+					m.Key = strings.TrimPrefix(x, g.Key)
+				}
+
+				g.Members = append(g.Members, m)
+			}
+
+			r.Imports = append(r.Imports, Import{Alias: g.Alias, Path: importPath(rel, f.Base()+"_pb")})
+			r.Groups = append(r.Groups, g)
 		}
 
-		err := afero.Walk(t.fileSystem, t.source, walkFunc)
-		if err != nil {
-			return nil, tracer.Mask(err)
+		// Resources without any messages or services do not have anything to
+		// export and are therefore omitted.
+		if len(r.Imports) == 0 {
+			continue
 		}
+
+		data = append(data, r)
 	}
 
-	return dirs, nil
+	return data, nil
 }
 
 func (t *Typescript) render(path string, tmpl string, data interface{}) ([]byte, error) {
-	f := template.FuncMap{
-		"ToResource": func(s string) string {
-			n := s
-
-			n = filepath.Base(n)
-			n = strings.Title(n)
-
-			return n
-		},
-	}
-
-	s, err := template.New(path).Funcs(f).Parse(tmpl)
+	s, err := template.New(path).Parse(tmpl)
 	if err != nil {
 		return nil, tracer.Mask(err)
 	}
@@ -201,3 +290,44 @@ func (t *Typescript) render(path string, tmpl string, data interface{}) ([]byte,
 
 	return b.Bytes(), nil
 }
+
+// importPath returns the relative module path of a generated file within the
+// destination directory, e.g. "./pbf/user/create_pb".
+func importPath(dir string, name string) string {
+	return "./" + filepath.ToSlash(filepath.Join(dir, name))
+}
+
+// toCamel converts file and directory names into valid typescript
+// identifiers, e.g. "list_items" into "ListItems".
+func toCamel(s string) string {
+	f := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+
+	var n string
+	for _, x := range strings.FieldsFunc(s, f) {
+		n += strings.Title(x)
+	}
+
+	return n
+}
+
+// toUpperFirst converts the first character of the given string to upper case
+// the same way grpc-web does when naming its generated client modules, e.g.
+// "Api" for "api".
+func toUpperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+
+	return string(r)
+}
+
+// toResource returns the resource name of the given directory, e.g. "User" for
+// "pbf/user".
+func toResource(s string) string {
+	return toCamel(filepath.Base(s))
+}
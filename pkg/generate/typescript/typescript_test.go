@@ -3,6 +3,7 @@ package typescript
 import (
 	"bytes"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"sort"
@@ -24,8 +25,7 @@ var update = flag.Bool("update", false, "update .golden files")
 // language specific code. The tests here ensure that the command execution with
 // its flags and positional arguments works as expected.
 //
-//     go test ./pkg/generate/typescript -run Test_Typescript_Commands -update
-//
+//	go test ./pkg/generate/typescript -run Test_Typescript_Commands -update
 func Test_Typescript_Commands(t *testing.T) {
 	testCases := []struct {
 		fs  afero.Fs
@@ -219,37 +219,35 @@ func Test_Typescript_Commands(t *testing.T) {
 // packaged and used. The tests here ensure that the additional file generation
 // works according to the gRPC apischema.
 //
-//     go test ./... -run Test_Typescript_Files -update
-//
+//	go test ./... -run Test_Typescript_Files -update
 func Test_Typescript_Files(t *testing.T) {
 	testCases := []struct {
 		fs  afero.Fs
 		dst string
 		src string
 	}{
-		// Case 0 ensures that a single proto file in a single directory is
+		// Case 0 ensures that a single resource with the conventional create,
+		// delete, search and update operations in a single directory is
 		// scanned accordingly.
 		{
 			fs: func() afero.Fs {
 				fs := afero.NewMemMapFs()
 
-				mustCreateDir(fs, "pbf")
-				mustCreateFile(fs, "pbf/foo.proto")
+				mustCreateResource(fs, "pbf", "api", "create", "delete", "search", "update")
 
 				return fs
 			}(),
 			dst: "./src/",
 			src: ".",
 		},
-		// Case 1 ensures that a single proto file in a single directory is
+		// Case 1 ensures that a single resource in a single directory is
 		// scanned accordingly. Note that there are additional empty
 		// directories.
 		{
 			fs: func() afero.Fs {
 				fs := afero.NewMemMapFs()
 
-				mustCreateDir(fs, "pbf")
-				mustCreateFile(fs, "pbf/foo.proto")
+				mustCreateResource(fs, "pbf", "api", "create", "delete", "search", "update")
 
 				mustCreateDir(fs, "pbf/post")
 				mustCreateDir(fs, "pbf/user")
@@ -259,97 +257,126 @@ func Test_Typescript_Files(t *testing.T) {
 			dst: "some/other/dir",
 			src: ".",
 		},
-		// Case 2 ensures that multiple proto files in multiple directories are
-		// scanned accordingly.
+		// Case 2 ensures that multiple resources in multiple directories are
+		// scanned accordingly. Note that the user resource does not define any
+		// service.
 		{
 			fs: func() afero.Fs {
 				fs := afero.NewMemMapFs()
 
-				mustCreateDir(fs, "pbf/post")
-				mustCreateFile(fs, "pbf/post/api.proto")
-				mustCreateFile(fs, "pbf/post/create.proto")
-
-				mustCreateDir(fs, "pbf/user")
-				mustCreateFile(fs, "pbf/user/foo.proto")
-				mustCreateFile(fs, "pbf/user/bar.proto")
-				mustCreateFile(fs, "pbf/user/baz.proto")
+				mustCreateResource(fs, "pbf/post", "api", "create")
+				mustCreateResource(fs, "pbf/user", "foo", "bar", "baz")
 
 				return fs
 			}(),
 			dst: "./src/",
 			src: ".",
 		},
-		// Case 3 ensures that multiple proto files in multiple directories are
+		// Case 3 ensures that multiple resources in multiple directories are
 		// scanned accordingly. Note that the given destination path is slightly
 		// different.
 		{
 			fs: func() afero.Fs {
 				fs := afero.NewMemMapFs()
 
-				mustCreateDir(fs, "pbf/post")
-				mustCreateFile(fs, "pbf/post/api.proto")
-				mustCreateFile(fs, "pbf/post/create.proto")
-
-				mustCreateDir(fs, "pbf/user")
-				mustCreateFile(fs, "pbf/user/foo.proto")
-				mustCreateFile(fs, "pbf/user/bar.proto")
-				mustCreateFile(fs, "pbf/user/baz.proto")
+				mustCreateResource(fs, "pbf/post", "api", "create")
+				mustCreateResource(fs, "pbf/user", "foo", "bar", "baz")
 
 				return fs
 			}(),
 			dst: "src",
 			src: ".",
 		},
-		// Case 4 ensures that multiple proto files in multiple directories are
+		// Case 4 ensures that multiple resources in multiple directories are
 		// scanned accordingly. Note that the given destination path is
 		// absolute.
 		{
 			fs: func() afero.Fs {
 				fs := afero.NewMemMapFs()
 
-				mustCreateDir(fs, "pbf/post")
-				mustCreateFile(fs, "pbf/post/api.proto")
-				mustCreateFile(fs, "pbf/post/create.proto")
+				mustCreateResource(fs, "pbf/post", "api", "create")
+				mustCreateResource(fs, "pbf/user", "foo", "bar", "baz")
+				mustCreateResource(fs, "pbf/more/deeply/nested", "api", "foo", "bar", "baz")
 
-				mustCreateDir(fs, "pbf/user")
-				mustCreateFile(fs, "pbf/user/foo.proto")
-				mustCreateFile(fs, "pbf/user/bar.proto")
-				mustCreateFile(fs, "pbf/user/baz.proto")
+				return fs
+			}(),
+			dst: "/home/runner/tmp/src/",
+			src: ".",
+		},
+		// Case 5 ensures that only resources in the source directory are
+		// scanned accordingly. Note that the generated modules are located
+		// relative to the source directory.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
 
-				mustCreateDir(fs, "pbf/more/deeply/nested")
-				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
-				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
-				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+				mustCreateResource(fs, "pbf/post", "api", "create")
+				mustCreateResource(fs, "pbf/user", "api", "foo", "bar", "baz")
+				mustCreateResource(fs, "pbf/more/deeply/nested", "api", "foo", "bar", "baz")
 
 				return fs
 			}(),
-			dst: "/home/runner/tmp/src/",
+			dst: "./src/",
+			src: "./pbf/user/",
+		},
+		// Case 6 ensures that resources with only some of the conventional
+		// operations and additional operations like list are exported
+		// according to the proto files actually present.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateResource(fs, "pbf/post", "api", "create", "list")
+				mustCreateResource(fs, "pbf/user-group", "api", "search")
+
+				return fs
+			}(),
+			dst: "./src/",
 			src: ".",
 		},
-		// Case 5 ensures that only proto files in the source directory are
-		// scanned accordingly.
+		// Case 7 ensures that multiple services, enums and messages not
+		// following the naming conventions are exported as well.
 		{
 			fs: func() afero.Fs {
 				fs := afero.NewMemMapFs()
 
-				mustCreateDir(fs, "pbf/post")
-				mustCreateFile(fs, "pbf/post/api.proto")
-				mustCreateFile(fs, "pbf/post/create.proto")
+				mustCreateProto(fs, "pbf/user/admin.proto", `
+syntax = "proto3";
 
-				mustCreateDir(fs, "pbf/user")
-				mustCreateFile(fs, "pbf/user/foo.proto")
-				mustCreateFile(fs, "pbf/user/bar.proto")
-				mustCreateFile(fs, "pbf/user/baz.proto")
+service Admin {
+  rpc Ban(BanI) returns (BanO);
+}
 
-				mustCreateDir(fs, "pbf/more/deeply/nested")
-				mustCreateFile(fs, "pbf/more/deeply/nested/foo.proto")
-				mustCreateFile(fs, "pbf/more/deeply/nested/bar.proto")
-				mustCreateFile(fs, "pbf/more/deeply/nested/baz.proto")
+message BanI {}
+message BanO {}
+`)
+				mustCreateProto(fs, "pbf/user/api.proto", `
+syntax = "proto3";
+
+service API {
+  rpc Create(CreateI) returns (CreateO);
+}
+`)
+				mustCreateProto(fs, "pbf/user/create.proto", `
+syntax = "proto3";
+
+message CreateI {
+  Kind kind = 1;
+}
+
+message CreateO {}
+
+message Obj {}
+
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+}
+`)
 
 				return fs
 			}(),
 			dst: "./src/",
-			src: "./pbf/user/",
+			src: ".",
 		},
 	}
 
@@ -425,3 +452,34 @@ func mustCreateFile(fs afero.Fs, p string) {
 		panic(err)
 	}
 }
+
+func mustCreateProto(fs afero.Fs, p string, s string) {
+	err := afero.WriteFile(fs, p, []byte(s), 0644)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// mustCreateResource creates conventional proto files for the given resource
+// directory. The proto file "api" defines the resource's service with one rpc
+// per additional file. Every additional file defines the input and output
+// messages of its operation, e.g. CreateI and CreateO for "create".
+func mustCreateResource(fs afero.Fs, d string, files ...string) {
+	var rpcs []string
+	for _, f := range files {
+		if f == "api" {
+			continue
+		}
+
+		n := strings.Title(f)
+
+		rpcs = append(rpcs, fmt.Sprintf("  rpc %s(%sI) returns (%sO);", n, n, n))
+		mustCreateProto(fs, filepath.Join(d, f+".proto"), fmt.Sprintf("syntax = \"proto3\";\n\nmessage %sI {}\nmessage %sO {}\n", n, n))
+	}
+
+	for _, f := range files {
+		if f == "api" {
+			mustCreateProto(fs, filepath.Join(d, f+".proto"), fmt.Sprintf("syntax = \"proto3\";\n\nservice API {\n%s\n}\n", strings.Join(rpcs, "\n")))
+		}
+	}
+}
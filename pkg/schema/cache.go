@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/xh3b4sd/tracer"
+)
+
+// Cache shares schemas across code generators so that every source is only
+// scanned once per process, even if multiple generators are executed for the
+// same source.
+type Cache struct {
+	mutex   sync.Mutex
+	schemas map[string]*Schema
+}
+
+func NewCache() *Cache {
+	c := &Cache{
+		schemas: map[string]*Schema{},
+	}
+
+	return c
+}
+
+// Schema returns the schema for the given configuration. Schemas are cached
+// by their source and excludes, which means that all configurations given to
+// the same cache have to use the same file system.
+func (c *Cache) Schema(config Config) (*Schema, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	k := strings.Join(append([]string{filepath.Clean(config.Source)}, config.Exclude...), "\x00")
+
+	s, ok := c.schemas[k]
+	if ok {
+		return s, nil
+	}
+
+	s, err := New(config)
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	c.schemas[k] = s
+
+	return s, nil
+}
+
+// Reset removes all cached schemas, so that every source is scanned again,
+// e.g. after protocol buffer files changed.
+func (c *Cache) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.schemas = map[string]*Schema{}
+}
+
+// Schemas returns all cached schemas.
+func (c *Cache) Schemas() []*Schema {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var l []*Schema
+	for _, s := range c.schemas {
+		l = append(l, s)
+	}
+
+	return l
+}
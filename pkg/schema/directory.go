@@ -0,0 +1,41 @@
+package schema
+
+import "path/filepath"
+
+// Directory is a single directory within the scanned source containing
+// protocol buffer files. Conventionally every directory describes a single
+// api resource like "pbf/user".
+type Directory struct {
+	// Path is the location of the directory as found on the file system, e.g.
+	// "pbf/user".
+	Path  string
+	Files []File
+}
+
+// Paths returns the file paths of all protocol buffer files within the
+// directory, e.g. "pbf/user/create.proto".
+func (d Directory) Paths() []string {
+	var l []string
+	for _, f := range d.Files {
+		l = append(l, f.Path)
+	}
+
+	return l
+}
+
+// Resource returns the name of the api resource described by the directory,
+// which is the last element of the directory path, e.g. "user" for
+// "pbf/user".
+func (d Directory) Resource() string {
+	return filepath.Base(d.Path)
+}
+
+// Services returns all services defined within the directory.
+func (d Directory) Services() []Service {
+	var l []Service
+	for _, f := range d.Files {
+		l = append(l, f.Services...)
+	}
+
+	return l
+}
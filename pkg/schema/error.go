@@ -0,0 +1,23 @@
+package schema
+
+import (
+	"errors"
+
+	"github.com/xh3b4sd/tracer"
+)
+
+var invalidConfigError = &tracer.Error{
+	Kind: "invalidConfigError",
+}
+
+func IsInvalidConfig(err error) bool {
+	return errors.Is(err, invalidConfigError)
+}
+
+var parseFailedError = &tracer.Error{
+	Kind: "parseFailedError",
+}
+
+func IsParseFailed(err error) bool {
+	return errors.Is(err, parseFailedError)
+}
@@ -0,0 +1,133 @@
+package schema
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// File is the parsed representation of a single protocol buffer file.
+type File struct {
+	// Path is the location of the parsed file as found on the file system,
+	// e.g. "pbf/user/create.proto".
+	Path string
+	// Comment is the comment directly preceding the syntax statement.
+	Comment string
+	// Syntax is the declared syntax of the file, e.g. "proto3". Files without
+	// syntax statement leave this empty.
+	Syntax   string
+	Package  string
+	Imports  []Import
+	Options  []Option
+	Enums    []Enum
+	Extends  []Extend
+	Messages []Message
+	Services []Service
+}
+
+type Import struct {
+	Comment string
+	// Modifier is either empty or one of "public" and "weak".
+	Modifier string
+	Path     string
+}
+
+type Option struct {
+	// Name is the option name as written in the schema, e.g. "go_package" or
+	// "(google.api.http).get".
+	Name string
+	// Value is the option value. String literals are unquoted. Aggregate
+	// values are kept in their text format representation.
+	Value string
+}
+
+type Enum struct {
+	Name    string
+	Comment string
+	Options []Option
+	Values  []EnumValue
+}
+
+type EnumValue struct {
+	Name    string
+	Comment string
+	Number  int
+	Options []Option
+}
+
+type Extend struct {
+	Comment string
+	// Extendee is the message being extended, e.g.
+	// "google.protobuf.FieldOptions".
+	Extendee string
+	Fields   []Field
+}
+
+type Message struct {
+	Name     string
+	Comment  string
+	Enums    []Enum
+	Fields   []Field
+	Messages []Message
+	Oneofs   []Oneof
+	Options  []Option
+}
+
+type Field struct {
+	Name    string
+	Comment string
+	// Key is the key type of map fields. Key is empty for all other fields.
+	Key string
+	// Label is either empty or one of "optional" and "repeated".
+	Label  string
+	Number int
+	// Oneof is the name of the oneof this field is part of, if any.
+	Oneof   string
+	Options []Option
+	// Type is the field type as written in the schema, e.g. "string" or
+	// "google.protobuf.Timestamp". For map fields Type is the value type.
+	Type string
+}
+
+type Oneof struct {
+	Name    string
+	Comment string
+	Options []Option
+}
+
+type Service struct {
+	Name    string
+	Comment string
+	Methods []Method
+	Options []Option
+}
+
+type Method struct {
+	Name            string
+	Comment         string
+	Input           string
+	Output          string
+	ClientStreaming bool
+	ServerStreaming bool
+	Options         []Option
+}
+
+// Base returns the file name without directory and without ".proto"
+// extension, e.g. "create" for "pbf/user/create.proto".
+func (f File) Base() string {
+	return strings.TrimSuffix(filepath.Base(f.Path), filepath.Ext(f.Path))
+}
+
+// Option returns the value of the file level option with the given name.
+func (f File) Option(name string) (string, bool) {
+	return option(f.Options, name)
+}
+
+func option(l []Option, name string) (string, bool) {
+	for _, o := range l {
+		if o.Name == name {
+			return o.Value, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,344 @@
+package schema
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/xh3b4sd/tracer"
+)
+
+const (
+	kindComment = "comment"
+	kindEOF     = "eof"
+	kindIdent   = "ident"
+	kindNumber  = "number"
+	kindString  = "string"
+	kindSymbol  = "symbol"
+)
+
+type token struct {
+	// Kind is one of the token kinds defined above, e.g. "ident" or "symbol".
+	Kind string
+	// Text is the token value. String literals are unquoted and comments are
+	// stripped from their comment markers.
+	Text string
+	// Line is the 1-based line number the token starts at.
+	Line int
+	// Col is the 1-based column number the token starts at.
+	Col int
+	// End is the 1-based line number the token ends at. This is only
+	// different from Line for block comments spanning multiple lines.
+	End int
+}
+
+type lexer struct {
+	path string
+	src  []rune
+
+	col  int
+	line int
+	pos  int
+}
+
+func newLexer(path string, b []byte) *lexer {
+	return &lexer{
+		path: path,
+		src:  []rune(string(b)),
+
+		col:  1,
+		line: 1,
+	}
+}
+
+// tokens reads the complete source and returns all tokens including comments.
+// The last token is always of kind "eof".
+func (l *lexer) tokens() ([]token, error) {
+	var toks []token
+
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		toks = append(toks, t)
+
+		if t.Kind == kindEOF {
+			break
+		}
+	}
+
+	return toks, nil
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipSpace()
+
+	t := token{Line: l.line, Col: l.col}
+
+	if l.pos >= len(l.src) {
+		t.Kind = kindEOF
+		t.End = t.Line
+		return t, nil
+	}
+
+	r := l.src[l.pos]
+
+	switch {
+	case r == '/' && l.peek(1) == '/':
+		l.advance(2)
+		s := l.pos
+		for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+			l.advance(1)
+		}
+		t.Kind = kindComment
+		t.Text = strings.TrimPrefix(string(l.src[s:l.pos]), " ")
+
+	case r == '/' && l.peek(1) == '*':
+		l.advance(2)
+		s := l.pos
+		for {
+			if l.pos >= len(l.src) {
+				return token{}, tracer.Maskf(parseFailedError, "%s:%d:%d: unterminated block comment", l.path, t.Line, t.Col)
+			}
+			if l.src[l.pos] == '*' && l.peek(1) == '/' {
+				break
+			}
+			l.advance(1)
+		}
+		t.Kind = kindComment
+		t.Text = blockComment(string(l.src[s:l.pos]))
+		l.advance(2)
+
+	case r == '"' || r == '\'':
+		s, err := l.str(r)
+		if err != nil {
+			return token{}, tracer.Mask(err)
+		}
+		t.Kind = kindString
+		t.Text = s
+
+	case isDigit(r) || (r == '.' && isDigit(l.peek(1))):
+		s := l.pos
+		for l.pos < len(l.src) && isNumber(l.src[l.pos], l.src[l.pos-1]) {
+			l.advance(1)
+		}
+		t.Kind = kindNumber
+		t.Text = string(l.src[s:l.pos])
+
+	case isLetter(r):
+		s := l.pos
+		for l.pos < len(l.src) && (isLetter(l.src[l.pos]) || isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.advance(1)
+		}
+		t.Kind = kindIdent
+		t.Text = string(l.src[s:l.pos])
+
+	case strings.ContainsRune(";{}[]()<>=,:-+.", r):
+		l.advance(1)
+		t.Kind = kindSymbol
+		t.Text = string(r)
+
+	default:
+		return token{}, tracer.Maskf(parseFailedError, "%s:%d:%d: unexpected character %q", l.path, t.Line, t.Col, r)
+	}
+
+	t.End = l.line
+
+	return t, nil
+}
+
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.pos++
+	}
+}
+
+func (l *lexer) peek(n int) rune {
+	if l.pos+n >= len(l.src) {
+		return 0
+	}
+
+	return l.src[l.pos+n]
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.src) && unicode.IsSpace(l.src[l.pos]) {
+		l.advance(1)
+	}
+}
+
+// str reads a quoted string literal and returns its unescaped value. All
+// escape sequences of the protocol buffer language are translated. Hexadecimal
+// and octal escapes denote single bytes, while unicode escapes denote code
+// points written in UTF-8.
+func (l *lexer) str(q rune) (string, error) {
+	line, col := l.line, l.col
+
+	l.advance(1)
+
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return "", tracer.Maskf(parseFailedError, "%s:%d:%d: unterminated string literal", l.path, line, col)
+		}
+
+		r := l.src[l.pos]
+		if r == q {
+			l.advance(1)
+			break
+		}
+
+		if r == '\\' {
+			err := l.escape(&b)
+			if err != nil {
+				return "", tracer.Mask(err)
+			}
+
+			continue
+		}
+
+		b.WriteRune(r)
+		l.advance(1)
+	}
+
+	return b.String(), nil
+}
+
+// escape reads the escape sequence at the current position and writes its
+// unescaped value to b.
+func (l *lexer) escape(b *strings.Builder) error {
+	line, col := l.line, l.col
+
+	l.advance(1)
+
+	e := l.peek(0)
+	switch e {
+	case 'a':
+		b.WriteByte('\a')
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case 'v':
+		b.WriteByte('\v')
+	case '\\', '\'', '"', '?':
+		b.WriteRune(e)
+
+	case 'x', 'X':
+		l.advance(1)
+		n, ok := l.digits(16, 1, 2)
+		if !ok {
+			return tracer.Maskf(parseFailedError, "%s:%d:%d: invalid hexadecimal escape", l.path, line, col)
+		}
+		b.WriteByte(byte(n))
+		return nil
+
+	case 'u', 'U':
+		d := 4
+		if e == 'U' {
+			d = 8
+		}
+		l.advance(1)
+		n, ok := l.digits(16, d, d)
+		if !ok || n > unicode.MaxRune || (n >= 0xd800 && n <= 0xdfff) {
+			return tracer.Maskf(parseFailedError, "%s:%d:%d: invalid unicode escape", l.path, line, col)
+		}
+		b.WriteRune(rune(n))
+		return nil
+
+	default:
+		if e < '0' || e > '7' {
+			return tracer.Maskf(parseFailedError, "%s:%d:%d: invalid escape sequence", l.path, line, col)
+		}
+		n, _ := l.digits(8, 1, 3)
+		if n > 0xff {
+			return tracer.Maskf(parseFailedError, "%s:%d:%d: invalid octal escape", l.path, line, col)
+		}
+		b.WriteByte(byte(n))
+		return nil
+	}
+
+	l.advance(1)
+
+	return nil
+}
+
+// digits reads at least min and at most max digits of the given base and
+// returns their value. The returned flag is false if less than min digits
+// could be read.
+func (l *lexer) digits(base int, min int, max int) (int, bool) {
+	var n, i int
+	for ; i < max; i++ {
+		v, ok := digit(l.peek(0), base)
+		if !ok {
+			break
+		}
+		n = n*base + v
+		l.advance(1)
+	}
+
+	return n, i >= min
+}
+
+// blockComment strips the leading asterisks commonly used to align block
+// comments and returns the remaining text lines.
+func blockComment(s string) string {
+	var l []string
+	for _, x := range strings.Split(s, "\n") {
+		x = strings.TrimSpace(x)
+		x = strings.TrimPrefix(x, "*")
+		x = strings.TrimPrefix(x, " ")
+		l = append(l, x)
+	}
+
+	return strings.TrimSpace(strings.Join(l, "\n"))
+}
+
+// digit returns the value of r as a digit of the given base.
+func digit(r rune, base int) (int, bool) {
+	var v int
+	switch {
+	case r >= '0' && r <= '9':
+		v = int(r - '0')
+	case r >= 'a' && r <= 'f':
+		v = int(r-'a') + 10
+	case r >= 'A' && r <= 'F':
+		v = int(r-'A') + 10
+	default:
+		return 0, false
+	}
+
+	return v, v < base
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isLetter(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// isNumber reports whether r continues a numeric literal given the previous
+// rune p. This covers decimal, octal, hexadecimal and floating point literals
+// including exponents like "1e-10".
+func isNumber(r rune, p rune) bool {
+	if isDigit(r) || isLetter(r) || r == '.' {
+		return true
+	}
+
+	return (r == '-' || r == '+') && (p == 'e' || p == 'E')
+}
@@ -0,0 +1,987 @@
+package schema
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/xh3b4sd/tracer"
+)
+
+// Parse parses the given protocol buffer source into its typed
+// representation. The given path is only used for the resulting File and for
+// error messages. Parse understands the complete proto3 language including
+// comments, options and nested definitions, as well as proto2 groups. Note
+// that no type resolution is done. Referenced types are kept as written in the schema.
+func Parse(path string, b []byte) (File, error) {
+	toks, err := newLexer(path, b).tokens()
+	if err != nil {
+		return File{}, tracer.Mask(err)
+	}
+
+	p := &parser{
+		path: path,
+		toks: toks,
+	}
+
+	f, err := p.file()
+	if err != nil {
+		return File{}, tracer.Mask(err)
+	}
+
+	return f, nil
+}
+
+type parser struct {
+	path string
+	toks []token
+
+	// comment is the leading comment of the token most recently returned by
+	// next.
+	comment string
+	// last is the line of the last non comment token returned by next. Comments
+	// on that line are trailing comments and never lead the next statement.
+	last int
+	pos  int
+}
+
+func (p *parser) file() (File, error) {
+	f := File{
+		Path: p.path,
+	}
+
+	for {
+		t := p.next()
+		c := p.comment
+
+		switch {
+		case t.Kind == kindEOF:
+			return f, nil
+
+		case t.Text == ";":
+
+		case t.Text == "syntax" || t.Text == "edition":
+			err := p.expect("=")
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			s, err := p.str()
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			err = p.expect(";")
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			f.Comment = c
+			f.Syntax = s
+
+		case t.Text == "package":
+			s, err := p.ident()
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			err = p.expect(";")
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			f.Package = s
+
+		case t.Text == "import":
+			i := Import{Comment: c}
+			if n := p.peek(0); n.Text == "public" || n.Text == "weak" {
+				i.Modifier = p.next().Text
+			}
+			s, err := p.str()
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			err = p.expect(";")
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			i.Path = s
+			f.Imports = append(f.Imports, i)
+
+		case t.Text == "option":
+			o, err := p.option(";")
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			f.Options = append(f.Options, o)
+
+		case t.Text == "enum":
+			e, err := p.enum(c)
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			f.Enums = append(f.Enums, e)
+
+		case t.Text == "extend":
+			e, l, err := p.extend(c)
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			f.Extends = append(f.Extends, e)
+			f.Messages = append(f.Messages, l...)
+
+		case t.Text == "message":
+			m, err := p.message(c)
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			f.Messages = append(f.Messages, m)
+
+		case t.Text == "service":
+			s, err := p.service(c)
+			if err != nil {
+				return File{}, tracer.Mask(err)
+			}
+			f.Services = append(f.Services, s)
+
+		default:
+			return File{}, p.unexpected(t, "top level statement")
+		}
+	}
+}
+
+func (p *parser) enum(comment string) (Enum, error) {
+	var err error
+
+	e := Enum{Comment: comment}
+
+	e.Name, err = p.ident()
+	if err != nil {
+		return Enum{}, tracer.Mask(err)
+	}
+
+	err = p.expect("{")
+	if err != nil {
+		return Enum{}, tracer.Mask(err)
+	}
+
+	for {
+		t := p.next()
+		c := p.comment
+
+		switch {
+		case t.Text == "}":
+			return e, nil
+
+		case t.Kind == kindEOF:
+			return Enum{}, p.unexpected(t, `"}"`)
+
+		case t.Text == ";":
+
+		case t.Text == "option" && p.peek(0).Text != "=":
+			o, err := p.option(";")
+			if err != nil {
+				return Enum{}, tracer.Mask(err)
+			}
+			e.Options = append(e.Options, o)
+
+		case t.Text == "reserved" && p.peek(0).Text != "=":
+			err := p.skip(";")
+			if err != nil {
+				return Enum{}, tracer.Mask(err)
+			}
+
+		case t.Kind == kindIdent:
+			v := EnumValue{Name: t.Text, Comment: c}
+
+			err := p.expect("=")
+			if err != nil {
+				return Enum{}, tracer.Mask(err)
+			}
+			v.Number, err = p.number()
+			if err != nil {
+				return Enum{}, tracer.Mask(err)
+			}
+			v.Options, err = p.options()
+			if err != nil {
+				return Enum{}, tracer.Mask(err)
+			}
+			err = p.expect(";")
+			if err != nil {
+				return Enum{}, tracer.Mask(err)
+			}
+
+			e.Values = append(e.Values, v)
+
+		default:
+			return Enum{}, p.unexpected(t, "enum value")
+		}
+	}
+}
+
+func (p *parser) extend(comment string) (Extend, []Message, error) {
+	var err error
+
+	e := Extend{Comment: comment}
+
+	e.Extendee, err = p.typ()
+	if err != nil {
+		return Extend{}, nil, tracer.Mask(err)
+	}
+
+	err = p.expect("{")
+	if err != nil {
+		return Extend{}, nil, tracer.Mask(err)
+	}
+
+	var n []Message
+	for {
+		t := p.next()
+		c := p.comment
+
+		switch {
+		case t.Text == "}":
+			return e, n, nil
+
+		case t.Kind == kindEOF:
+			return Extend{}, nil, p.unexpected(t, `"}"`)
+
+		case t.Text == ";":
+
+		case p.isGroup(t):
+			f, m, err := p.group(t, c)
+			if err != nil {
+				return Extend{}, nil, tracer.Mask(err)
+			}
+			e.Fields = append(e.Fields, f)
+			n = append(n, m)
+
+		default:
+			f, err := p.field(t, c)
+			if err != nil {
+				return Extend{}, nil, tracer.Mask(err)
+			}
+			e.Fields = append(e.Fields, f)
+		}
+	}
+}
+
+func (p *parser) message(comment string) (Message, error) {
+	var err error
+
+	m := Message{Comment: comment}
+
+	m.Name, err = p.ident()
+	if err != nil {
+		return Message{}, tracer.Mask(err)
+	}
+
+	return p.body(m)
+}
+
+// body parses the body of the given message including its curly braces.
+func (p *parser) body(m Message) (Message, error) {
+	err := p.expect("{")
+	if err != nil {
+		return Message{}, tracer.Mask(err)
+	}
+
+	for {
+		t := p.next()
+		c := p.comment
+
+		// Keywords may also be used as field types or field names. A keyword
+		// is only treated as such if the statement does not look like a field
+		// definition.
+		keyword := p.peek(0).Text != "=" && p.peek(1).Text != "="
+
+		switch {
+		case t.Text == "}":
+			return m, nil
+
+		case t.Kind == kindEOF:
+			return Message{}, p.unexpected(t, `"}"`)
+
+		case t.Text == ";":
+
+		case t.Text == "option":
+			o, err := p.option(";")
+			if err != nil {
+				return Message{}, tracer.Mask(err)
+			}
+			m.Options = append(m.Options, o)
+
+		case (t.Text == "reserved" || t.Text == "extensions") && keyword:
+			err := p.skip(";")
+			if err != nil {
+				return Message{}, tracer.Mask(err)
+			}
+
+		case t.Text == "enum" && keyword:
+			e, err := p.enum(c)
+			if err != nil {
+				return Message{}, tracer.Mask(err)
+			}
+			m.Enums = append(m.Enums, e)
+
+		case t.Text == "extend" && keyword:
+			// Nested extensions are parsed for the sake of correctness but are
+			// not tracked since they do not affect the message itself. Groups
+			// declared within them are nested messages nonetheless.
+			_, l, err := p.extend(c)
+			if err != nil {
+				return Message{}, tracer.Mask(err)
+			}
+			m.Messages = append(m.Messages, l...)
+
+		case t.Text == "message" && keyword:
+			n, err := p.message(c)
+			if err != nil {
+				return Message{}, tracer.Mask(err)
+			}
+			m.Messages = append(m.Messages, n)
+
+		case t.Text == "oneof" && keyword:
+			o, l, n, err := p.oneof(c)
+			if err != nil {
+				return Message{}, tracer.Mask(err)
+			}
+			m.Oneofs = append(m.Oneofs, o)
+			m.Fields = append(m.Fields, l...)
+			m.Messages = append(m.Messages, n...)
+
+		case p.isGroup(t):
+			f, n, err := p.group(t, c)
+			if err != nil {
+				return Message{}, tracer.Mask(err)
+			}
+			m.Fields = append(m.Fields, f)
+			m.Messages = append(m.Messages, n)
+
+		default:
+			f, err := p.field(t, c)
+			if err != nil {
+				return Message{}, tracer.Mask(err)
+			}
+			m.Fields = append(m.Fields, f)
+		}
+	}
+}
+
+// field parses a field definition for which the given token t was already
+// consumed.
+func (p *parser) field(t token, comment string) (Field, error) {
+	var err error
+
+	f := Field{Comment: comment}
+
+	if t.Text == "optional" || t.Text == "repeated" || t.Text == "required" {
+		f.Label = t.Text
+		t = p.next()
+	}
+
+	if t.Text == "map" && p.peek(0).Text == "<" {
+		p.next()
+
+		f.Key, err = p.typ()
+		if err != nil {
+			return Field{}, tracer.Mask(err)
+		}
+		err = p.expect(",")
+		if err != nil {
+			return Field{}, tracer.Mask(err)
+		}
+		f.Type, err = p.typ()
+		if err != nil {
+			return Field{}, tracer.Mask(err)
+		}
+		err = p.expect(">")
+		if err != nil {
+			return Field{}, tracer.Mask(err)
+		}
+	} else {
+		p.back()
+
+		f.Type, err = p.typ()
+		if err != nil {
+			return Field{}, tracer.Mask(err)
+		}
+	}
+
+	f.Name, err = p.ident()
+	if err != nil {
+		return Field{}, tracer.Mask(err)
+	}
+	err = p.expect("=")
+	if err != nil {
+		return Field{}, tracer.Mask(err)
+	}
+	f.Number, err = p.number()
+	if err != nil {
+		return Field{}, tracer.Mask(err)
+	}
+	f.Options, err = p.options()
+	if err != nil {
+		return Field{}, tracer.Mask(err)
+	}
+	err = p.expect(";")
+	if err != nil {
+		return Field{}, tracer.Mask(err)
+	}
+
+	return f, nil
+}
+
+// isGroup reports whether the given token t starts a proto2 group, like
+// "optional group Result = 1 { ... }". Group names must start with an upper
+// case letter, which distinguishes groups from fields of a type called group.
+func (p *parser) isGroup(t token) bool {
+	n := 0
+	if t.Text == "optional" || t.Text == "repeated" || t.Text == "required" {
+		t = p.peek(0)
+		n = 1
+	}
+
+	name := p.peek(n).Text
+
+	return t.Text == "group" && name != "" && unicode.IsUpper(rune(name[0])) && p.peek(n+1).Text == "="
+}
+
+// group parses a proto2 group for which the given token t was already
+// consumed. A group declares a nested message and a field of that message
+// type at once. The field is named after the lower cased group name, just
+// like protoc does.
+func (p *parser) group(t token, comment string) (Field, Message, error) {
+	var err error
+
+	f := Field{Comment: comment}
+	m := Message{Comment: comment}
+
+	if t.Text != "group" {
+		f.Label = t.Text
+		p.next()
+	}
+
+	m.Name, err = p.ident()
+	if err != nil {
+		return Field{}, Message{}, tracer.Mask(err)
+	}
+	err = p.expect("=")
+	if err != nil {
+		return Field{}, Message{}, tracer.Mask(err)
+	}
+	f.Number, err = p.number()
+	if err != nil {
+		return Field{}, Message{}, tracer.Mask(err)
+	}
+	f.Options, err = p.options()
+	if err != nil {
+		return Field{}, Message{}, tracer.Mask(err)
+	}
+	m, err = p.body(m)
+	if err != nil {
+		return Field{}, Message{}, tracer.Mask(err)
+	}
+
+	f.Name = strings.ToLower(m.Name)
+	f.Type = m.Name
+
+	return f, m, nil
+}
+
+func (p *parser) oneof(comment string) (Oneof, []Field, []Message, error) {
+	var err error
+
+	o := Oneof{Comment: comment}
+
+	o.Name, err = p.ident()
+	if err != nil {
+		return Oneof{}, nil, nil, tracer.Mask(err)
+	}
+
+	err = p.expect("{")
+	if err != nil {
+		return Oneof{}, nil, nil, tracer.Mask(err)
+	}
+
+	var l []Field
+	var n []Message
+	for {
+		t := p.next()
+		c := p.comment
+
+		switch {
+		case t.Text == "}":
+			return o, l, n, nil
+
+		case t.Kind == kindEOF:
+			return Oneof{}, nil, nil, p.unexpected(t, `"}"`)
+
+		case t.Text == ";":
+
+		case t.Text == "option":
+			x, err := p.option(";")
+			if err != nil {
+				return Oneof{}, nil, nil, tracer.Mask(err)
+			}
+			o.Options = append(o.Options, x)
+
+		case p.isGroup(t):
+			f, m, err := p.group(t, c)
+			if err != nil {
+				return Oneof{}, nil, nil, tracer.Mask(err)
+			}
+			f.Oneof = o.Name
+			l = append(l, f)
+			n = append(n, m)
+
+		default:
+			f, err := p.field(t, c)
+			if err != nil {
+				return Oneof{}, nil, nil, tracer.Mask(err)
+			}
+			f.Oneof = o.Name
+			l = append(l, f)
+		}
+	}
+}
+
+func (p *parser) service(comment string) (Service, error) {
+	var err error
+
+	s := Service{Comment: comment}
+
+	s.Name, err = p.ident()
+	if err != nil {
+		return Service{}, tracer.Mask(err)
+	}
+
+	err = p.expect("{")
+	if err != nil {
+		return Service{}, tracer.Mask(err)
+	}
+
+	for {
+		t := p.next()
+		c := p.comment
+
+		switch {
+		case t.Text == "}":
+			return s, nil
+
+		case t.Kind == kindEOF:
+			return Service{}, p.unexpected(t, `"}"`)
+
+		case t.Text == ";":
+
+		case t.Text == "option":
+			o, err := p.option(";")
+			if err != nil {
+				return Service{}, tracer.Mask(err)
+			}
+			s.Options = append(s.Options, o)
+
+		case t.Text == "rpc":
+			m, err := p.method(c)
+			if err != nil {
+				return Service{}, tracer.Mask(err)
+			}
+			s.Methods = append(s.Methods, m)
+
+		default:
+			return Service{}, p.unexpected(t, "rpc")
+		}
+	}
+}
+
+func (p *parser) method(comment string) (Method, error) {
+	var err error
+
+	m := Method{Comment: comment}
+
+	m.Name, err = p.ident()
+	if err != nil {
+		return Method{}, tracer.Mask(err)
+	}
+
+	m.Input, m.ClientStreaming, err = p.signature()
+	if err != nil {
+		return Method{}, tracer.Mask(err)
+	}
+
+	err = p.expect("returns")
+	if err != nil {
+		return Method{}, tracer.Mask(err)
+	}
+
+	m.Output, m.ServerStreaming, err = p.signature()
+	if err != nil {
+		return Method{}, tracer.Mask(err)
+	}
+
+	t := p.next()
+	switch t.Text {
+	case ";":
+		return m, nil
+	case "{":
+	default:
+		return Method{}, p.unexpected(t, `";" or "{"`)
+	}
+
+	for {
+		t := p.next()
+
+		switch {
+		case t.Text == "}":
+			return m, nil
+
+		case t.Kind == kindEOF:
+			return Method{}, p.unexpected(t, `"}"`)
+
+		case t.Text == ";":
+
+		case t.Text == "option":
+			o, err := p.option(";")
+			if err != nil {
+				return Method{}, tracer.Mask(err)
+			}
+			m.Options = append(m.Options, o)
+
+		default:
+			return Method{}, p.unexpected(t, "option")
+		}
+	}
+}
+
+// signature parses the parenthesized message type of rpc inputs and outputs,
+// e.g. "(stream CreateI)".
+func (p *parser) signature() (string, bool, error) {
+	err := p.expect("(")
+	if err != nil {
+		return "", false, tracer.Mask(err)
+	}
+
+	var stream bool
+	if p.peek(0).Text == "stream" && p.peek(1).Text != ")" {
+		p.next()
+		stream = true
+	}
+
+	s, err := p.typ()
+	if err != nil {
+		return "", false, tracer.Mask(err)
+	}
+
+	err = p.expect(")")
+	if err != nil {
+		return "", false, tracer.Mask(err)
+	}
+
+	return s, stream, nil
+}
+
+// option parses an option statement after the "option" keyword up until the
+// given terminating symbol, which is consumed as well.
+func (p *parser) option(end string) (Option, error) {
+	var err error
+
+	var o Option
+
+	o.Name, err = p.optionName()
+	if err != nil {
+		return Option{}, tracer.Mask(err)
+	}
+
+	err = p.expect("=")
+	if err != nil {
+		return Option{}, tracer.Mask(err)
+	}
+
+	o.Value, err = p.constant()
+	if err != nil {
+		return Option{}, tracer.Mask(err)
+	}
+
+	if end != "" {
+		err = p.expect(end)
+		if err != nil {
+			return Option{}, tracer.Mask(err)
+		}
+	}
+
+	return o, nil
+}
+
+// options parses the optional compact options of fields and enum values, e.g.
+// `[deprecated = true, json_name = "foo"]`.
+func (p *parser) options() ([]Option, error) {
+	if p.peek(0).Text != "[" {
+		return nil, nil
+	}
+
+	p.next()
+
+	var l []Option
+	for {
+		o, err := p.option("")
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		l = append(l, o)
+
+		t := p.next()
+		switch t.Text {
+		case ",":
+		case "]":
+			return l, nil
+		default:
+			return nil, p.unexpected(t, `"," or "]"`)
+		}
+	}
+}
+
+func (p *parser) optionName() (string, error) {
+	var b strings.Builder
+
+	for {
+		t := p.next()
+
+		switch {
+		case t.Text == "(":
+			s, err := p.typ()
+			if err != nil {
+				return "", tracer.Mask(err)
+			}
+			err = p.expect(")")
+			if err != nil {
+				return "", tracer.Mask(err)
+			}
+			b.WriteString("(" + s + ")")
+
+		case t.Kind == kindIdent:
+			b.WriteString(t.Text)
+
+		default:
+			return "", p.unexpected(t, "option name")
+		}
+
+		if p.peek(0).Text != "." {
+			return b.String(), nil
+		}
+
+		p.next()
+		b.WriteString(".")
+	}
+}
+
+// constant parses option values. Scalar values are returned as written,
+// string literals are unquoted and concatenated, and aggregate values are
+// returned in their text format representation.
+func (p *parser) constant() (string, error) {
+	t := p.next()
+
+	switch {
+	case t.Kind == kindString:
+		s := t.Text
+		for p.peek(0).Kind == kindString {
+			s += p.next().Text
+		}
+		return s, nil
+
+	case t.Kind == kindIdent || t.Kind == kindNumber:
+		return t.Text, nil
+
+	case t.Text == "-" || t.Text == "+":
+		n := p.next()
+		if n.Kind != kindNumber && n.Kind != kindIdent {
+			return "", p.unexpected(n, "number")
+		}
+		if t.Text == "+" {
+			return n.Text, nil
+		}
+		return t.Text + n.Text, nil
+
+	case t.Text == "{":
+		return p.aggregate()
+
+	default:
+		return "", p.unexpected(t, "constant")
+	}
+}
+
+// aggregate returns the text format representation of an aggregate option
+// value for which the opening brace was already consumed.
+func (p *parser) aggregate() (string, error) {
+	l := []string{"{"}
+
+	n := 1
+	for n > 0 {
+		t := p.next()
+
+		switch {
+		case t.Kind == kindEOF:
+			return "", p.unexpected(t, `"}"`)
+		case t.Kind == kindSymbol && t.Text == "{":
+			n++
+		case t.Kind == kindSymbol && t.Text == "}":
+			n--
+		}
+
+		if t.Kind == kindString {
+			l = append(l, strconv.Quote(t.Text))
+		} else {
+			l = append(l, t.Text)
+		}
+	}
+
+	return strings.Join(l, " "), nil
+}
+
+// typ parses message and enum type references which may be fully qualified
+// using a leading dot, e.g. ".google.protobuf.Timestamp".
+func (p *parser) typ() (string, error) {
+	var s string
+	if p.peek(0).Text == "." {
+		p.next()
+		s = "."
+	}
+
+	i, err := p.ident()
+	if err != nil {
+		return "", tracer.Mask(err)
+	}
+
+	return s + i, nil
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.next()
+	if t.Kind != kindIdent {
+		return "", p.unexpected(t, "identifier")
+	}
+
+	return t.Text, nil
+}
+
+func (p *parser) number() (int, error) {
+	t := p.next()
+
+	var s string
+	if t.Text == "-" {
+		s = "-"
+		t = p.next()
+	}
+
+	if t.Kind != kindNumber {
+		return 0, p.unexpected(t, "number")
+	}
+
+	i, err := strconv.ParseInt(s+t.Text, 0, 64)
+	if err != nil {
+		return 0, tracer.Maskf(parseFailedError, "%s:%d:%d: invalid number %q", p.path, t.Line, t.Col, t.Text)
+	}
+
+	return int(i), nil
+}
+
+func (p *parser) str() (string, error) {
+	t := p.next()
+	if t.Kind != kindString {
+		return "", p.unexpected(t, "string")
+	}
+
+	return t.Text, nil
+}
+
+func (p *parser) expect(s string) error {
+	t := p.next()
+	if t.Text != s || t.Kind == kindString {
+		return p.unexpected(t, strconv.Quote(s))
+	}
+
+	return nil
+}
+
+// skip consumes all tokens up to and including the given symbol.
+func (p *parser) skip(s string) error {
+	for {
+		t := p.next()
+		if t.Kind == kindEOF {
+			return p.unexpected(t, strconv.Quote(s))
+		}
+		if t.Text == s && t.Kind == kindSymbol {
+			return nil
+		}
+	}
+}
+
+func (p *parser) unexpected(t token, expected string) error {
+	if t.Kind == kindEOF {
+		return tracer.Maskf(parseFailedError, "%s:%d:%d: expected %s but got end of file", p.path, t.Line, t.Col, expected)
+	}
+
+	return tracer.Maskf(parseFailedError, "%s:%d:%d: expected %s but got %q", p.path, t.Line, t.Col, expected, t.Text)
+}
+
+// next returns the next non comment token and tracks its leading comment.
+// Comments are leading if they are not trailing the previous statement and if
+// there is no empty line between them and the returned token.
+func (p *parser) next() token {
+	var block []token
+
+	for {
+		t := p.toks[p.pos]
+		if t.Kind == kindEOF {
+			p.comment = ""
+			return t
+		}
+
+		p.pos++
+
+		if t.Kind != kindComment {
+			p.comment = ""
+			if len(block) != 0 && block[len(block)-1].End >= t.Line-1 {
+				var l []string
+				for _, c := range block {
+					l = append(l, c.Text)
+				}
+				p.comment = strings.Join(l, "\n")
+			}
+			p.last = t.End
+			return t
+		}
+
+		if p.last != 0 && t.Line == p.last {
+			continue
+		}
+
+		if len(block) != 0 && t.Line > block[len(block)-1].End+1 {
+			block = nil
+		}
+
+		block = append(block, t)
+	}
+}
+
+// back moves the parser back to the last non comment token so that it is
+// returned by the next call to next again.
+func (p *parser) back() {
+	for p.pos > 0 {
+		p.pos--
+		if p.toks[p.pos].Kind != kindComment {
+			return
+		}
+	}
+}
+
+// peek returns the n-th upcoming non comment token without consuming it.
+func (p *parser) peek(n int) token {
+	for i := p.pos; i < len(p.toks); i++ {
+		t := p.toks[i]
+		if t.Kind == kindComment {
+			continue
+		}
+		if t.Kind == kindEOF || n == 0 {
+			return t
+		}
+		n--
+	}
+
+	return p.toks[len(p.toks)-1]
+}
@@ -0,0 +1,209 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/afero"
+	"github.com/xh3b4sd/tracer"
+)
+
+const (
+	// Extension is the file extension of protocol buffer files.
+	Extension = ".proto"
+)
+
+type Config struct {
+	FileSystem afero.Fs
+
+	// Exclude is the list of files and directories to ignore while scanning
+	// the source. Entries are either paths like "pbf/internal" or glob
+	// patterns like "pbf/*/legacy.proto".
+	Exclude []string
+	Source  string
+}
+
+// Schema scans a source directory for protocol buffer files and parses them
+// into their typed representation so that code generators can make decisions
+// based on the actual api definitions.
+type Schema struct {
+	fileSystem afero.Fs
+	mutex      sync.Mutex
+
+	directories []Directory
+	exclude     []string
+	source      string
+}
+
+func New(config Config) (*Schema, error) {
+	if config.FileSystem == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.FileSystem must not be empty", config)
+	}
+
+	if config.Source == "" {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Source must not be empty", config)
+	}
+
+	s := &Schema{
+		fileSystem: config.FileSystem,
+
+		exclude: config.Exclude,
+		source:  config.Source,
+	}
+
+	return s, nil
+}
+
+// Directories walks the configured source and returns all directories
+// containing protocol buffer files together with their parsed files.
+// Directories are sorted by path and files within a directory are sorted by
+// path too. The source is only scanned once. Subsequent calls return the
+// result of the first successful scan.
+func (s *Schema) Directories() ([]Directory, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.directories != nil {
+		return s.directories, nil
+	}
+
+	l, err := s.scan()
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	s.directories = l
+
+	return l, nil
+}
+
+// Fingerprint walks the configured source the same way Directories does and
+// returns the size and modification time of every protocol buffer file found.
+// Comparing fingerprints tells whether protocol buffer files got created,
+// changed or removed. Fingerprints are never cached.
+func (s *Schema) Fingerprint() (map[string]string, error) {
+	m := map[string]string{}
+
+	err := s.walk(func(p string, i os.FileInfo) {
+		m[p] = fmt.Sprintf("%d %d", i.Size(), i.ModTime().UnixNano())
+	})
+	if err != nil {
+		return nil, tracer.Mask(err)
+	}
+
+	return m, nil
+}
+
+func (s *Schema) scan() ([]Directory, error) {
+	dirs := map[string][]string{}
+	{
+		err := s.walk(func(p string, i os.FileInfo) {
+			dirs[filepath.Dir(p)] = append(dirs[filepath.Dir(p)], filepath.Join(filepath.Dir(p), i.Name()))
+		})
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+	}
+
+	var l []Directory
+	for d, paths := range dirs {
+		sort.Strings(paths)
+
+		var files []File
+		for _, p := range paths {
+			b, err := afero.ReadFile(s.fileSystem, p)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			f, err := Parse(p, b)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			files = append(files, f)
+		}
+
+		l = append(l, Directory{Path: d, Files: files})
+	}
+
+	sort.Slice(l, func(i, j int) bool { return l[i].Path < l[j].Path })
+
+	return l, nil
+}
+
+// walk calls f for every protocol buffer file within the configured source,
+// which is not excluded.
+func (s *Schema) walk(f func(p string, i os.FileInfo)) error {
+	walkFunc := func(p string, i os.FileInfo, err error) error {
+		if err != nil {
+			return tracer.Mask(err)
+		}
+
+		if i.IsDir() && i.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if i.IsDir() && i.Name() == ".github" {
+			return filepath.SkipDir
+		}
+
+		if Excluded(p, s.exclude) {
+			if i.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		// We do not want to track directories. We are interested in
+		// directories containing specific files.
+		if i.IsDir() {
+			return nil
+		}
+
+		// We do not want to track files with the wrong extension. We are
+		// interested in protocol buffer files having the ".proto"
+		// extension.
+		if filepath.Ext(i.Name()) != Extension {
+			return nil
+		}
+
+		f(p, i)
+
+		return nil
+	}
+
+	err := afero.Walk(s.fileSystem, s.source, walkFunc)
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	return nil
+}
+
+// Excluded returns whether the given path is matched by any of the given
+// exclude entries. A path is matched if it equals an entry, if it is located
+// within an entry's directory or if it matches an entry's glob pattern.
+func Excluded(p string, exclude []string) bool {
+	p = filepath.Clean(p)
+
+	for _, e := range exclude {
+		e = filepath.Clean(e)
+
+		if p == e || strings.HasPrefix(p, e+string(filepath.Separator)) {
+			return true
+		}
+
+		m, err := filepath.Match(e, p)
+		if err == nil && m {
+			return true
+		}
+	}
+
+	return false
+}
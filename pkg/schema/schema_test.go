@@ -0,0 +1,499 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+var update = flag.Bool("update", false, "update .golden files")
+
+// Test_Schema_Parse tests the parsing of protocol buffer files. The typed
+// representation of the parsed schema is what code generators base their
+// decisions on. The tests here ensure that all relevant language elements are
+// parsed as expected.
+//
+//	go test ./pkg/schema -run Test_Schema_Parse -update
+func Test_Schema_Parse(t *testing.T) {
+	testCases := []struct {
+		src string
+	}{
+		// Case 0 ensures that empty files can be parsed.
+		{
+			src: ``,
+		},
+		// Case 1 ensures that messages with all kinds of fields, nested
+		// messages and enums as well as comments are parsed.
+		{
+			src: `// Package user defines the user resource.
+syntax = "proto3";
+
+package user;
+
+option go_package = "github.com/xh3b4sd/pag/pkg/pbf/user";
+
+import "google/protobuf/timestamp.proto";
+
+// CreateI is the input for creating users.
+message CreateI {
+  // Obj contains the objects to create.
+  repeated CreateI_Obj obj = 1;
+}
+
+message CreateI_Obj {
+  optional string name = 1; // trailing comment
+  map<string, string> labels = 2;
+
+  /*
+   * Created is the creation time.
+   */
+  .google.protobuf.Timestamp created = 3;
+  Kind kind = 4 [deprecated = true, json_name = "k"];
+
+  // Detached comments are not attached.
+
+  Nested nested = 5;
+
+  message Nested {
+    int64 count = 0x10;
+  }
+
+  enum Kind {
+    KIND_UNSPECIFIED = 0;
+    KIND_ADMIN = 1;
+  }
+}
+
+message CreateO {}
+`,
+		},
+		// Case 2 ensures that services with unary and streaming rpcs are
+		// parsed.
+		{
+			src: `syntax = "proto3";
+
+package user;
+
+import "pbf/user/create.proto";
+import public "pbf/user/delete.proto";
+
+// API is the user service.
+service API {
+  option deprecated = false;
+
+  // Create creates users.
+  rpc Create(CreateI) returns (CreateO);
+  rpc Delete(stream DeleteI) returns (stream DeleteO) {
+    option idempotency_level = IDEMPOTENT;
+  };
+  rpc Search(user.SearchI) returns (stream .user.SearchO) {}
+}
+`,
+		},
+		// Case 3 ensures that custom, aggregate and negative options as well
+		// as oneofs, reserved ranges and extensions are parsed.
+		{
+			src: `syntax = "proto3";
+
+package pag.test;
+
+import "google/api/annotations.proto";
+import "google/protobuf/descriptor.proto";
+
+option java_package = "com.example" ".test";
+option (pag.flag).enabled = true;
+
+extend google.protobuf.FieldOptions {
+  string label = 50000;
+}
+
+enum Level {
+  option allow_alias = true;
+  LEVEL_UNSPECIFIED = 0;
+  LEVEL_LOW = -1 [(pag.label) = "low"];
+  LEVEL_MIN = -1;
+  reserved 5 to 10;
+}
+
+message SearchI {
+  reserved 2, 4 to 6;
+  reserved "old";
+
+  oneof filter {
+    option (pag.oneof) = 1;
+    string id = 1;
+    string name = 3;
+  }
+
+  float ratio = 7 [default = -1.5e-3];
+}
+
+service API {
+  rpc Search(SearchI) returns (SearchI) {
+    option (google.api.http) = {
+      get: "/v1/search"
+      additional_bindings { get: "/v1/find" }
+    };
+  }
+}
+`,
+		},
+		// Case 4 ensures that proto2 groups are parsed into nested messages
+		// and fields within messages, oneofs and extensions.
+		{
+			src: `syntax = "proto2";
+
+package pag.test;
+
+message SearchO {
+  extensions 100 to 200;
+
+  // Result is a single search result.
+  repeated group Result = 1 {
+    required string url = 2;
+    optional string title = 3 [default = "\x41\101\u00e9"];
+  }
+
+  oneof paging {
+    group Cursor = 4 {
+      optional string next = 5;
+    }
+  }
+
+  optional group group = 6;
+}
+
+extend SearchO {
+  optional group Debug = 100 {
+    optional string trace = 101;
+  }
+}
+`,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			f, err := Parse("pbf/test.proto", []byte(tc.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := json.MarshalIndent(f, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, '\n')
+
+			p := filepath.Join("testdata/parse", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Schema_Parse_Error tests that invalid protocol buffer files cause
+// parse errors pointing to the location of the problem.
+func Test_Schema_Parse_Error(t *testing.T) {
+	testCases := []struct {
+		src string
+		err string
+	}{
+		// Case 0 ensures that a missing semicolon is detected.
+		{
+			src: "syntax = \"proto3\"\npackage user;",
+			err: `pbf/test.proto:2:1: expected ";" but got "package"`,
+		},
+		// Case 1 ensures that unterminated messages are detected.
+		{
+			src: "syntax = \"proto3\";\n\nmessage Foo {\n  string bar = 1;\n",
+			err: `pbf/test.proto:5:1: expected "}" but got end of file`,
+		},
+		// Case 2 ensures that unterminated strings are detected.
+		{
+			src: "syntax = \"proto3;\n",
+			err: `pbf/test.proto:1:10: unterminated string literal`,
+		},
+		// Case 3 ensures that unknown top level statements are detected.
+		{
+			src: "syntax = \"proto3\";\n\nmesage Foo {}\n",
+			err: `pbf/test.proto:3:1: expected top level statement but got "mesage"`,
+		},
+		// Case 4 ensures that unknown escape sequences are detected.
+		{
+			src: "syntax = \"proto\\q\";\n",
+			err: `pbf/test.proto:1:16: invalid escape sequence`,
+		},
+		// Case 5 ensures that hexadecimal escapes without digits are detected.
+		{
+			src: "option foo = \"\\xg\";\n",
+			err: `pbf/test.proto:1:15: invalid hexadecimal escape`,
+		},
+		// Case 6 ensures that incomplete unicode escapes are detected.
+		{
+			src: "option foo = \"\\u12\";\n",
+			err: `pbf/test.proto:1:15: invalid unicode escape`,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			_, err := Parse("pbf/test.proto", []byte(tc.src))
+			if !IsParseFailed(err) {
+				t.Fatalf("expected parseFailedError got %#v", err)
+			}
+
+			if err.Error() != tc.err {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.err, err.Error()))
+			}
+		})
+	}
+}
+
+// Test_Schema_Lexer_String tests the unescaping of string literals. The tests
+// here ensure that all escape sequences of the protocol buffer language are
+// translated.
+func Test_Schema_Lexer_String(t *testing.T) {
+	testCases := []struct {
+		src string
+		str string
+	}{
+		// Case 0 ensures that strings without escape sequences are kept.
+		{
+			src: `"foo bar"`,
+			str: "foo bar",
+		},
+		// Case 1 ensures that single quoted strings are supported.
+		{
+			src: `'foo "bar"'`,
+			str: `foo "bar"`,
+		},
+		// Case 2 ensures that character escapes are translated.
+		{
+			src: `"\a\b\f\n\r\t\v\\\'\"\?"`,
+			str: "\a\b\f\n\r\t\v\\'\"?",
+		},
+		// Case 3 ensures that hexadecimal escapes with one or two digits are
+		// translated into single bytes.
+		{
+			src: `"\x41\X4a\x7zz\xff"`,
+			str: "AJ\x07zz\xff",
+		},
+		// Case 4 ensures that octal escapes with up to three digits are
+		// translated into single bytes.
+		{
+			src: `"\101\0\12\1234"`,
+			str: "A\x00\nS4",
+		},
+		// Case 5 ensures that unicode escapes are translated into UTF-8.
+		{
+			src: `"\u00e9\U0001F600"`,
+			str: "é😀",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			toks, err := newLexer("pbf/test.proto", []byte(tc.src)).tokens()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if toks[0].Kind != kindString {
+				t.Fatalf("expected %s got %s", kindString, toks[0].Kind)
+			}
+
+			if toks[0].Text != tc.str {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.str, toks[0].Text))
+			}
+		})
+	}
+}
+
+// Test_Schema_Directories tests the scanning of source directories. The tests
+// here ensure that protocol buffer files are grouped by their directories
+// and that files outside of the configured source are ignored.
+//
+//	go test ./pkg/schema -run Test_Schema_Directories -update
+func Test_Schema_Directories(t *testing.T) {
+	testCases := []struct {
+		fs  afero.Fs
+		exc []string
+		src string
+	}{
+		// Case 0 ensures that multiple proto files in multiple directories are
+		// scanned and parsed.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateFile(fs, "pbf/user/api.proto", "service API {}")
+				mustCreateFile(fs, "pbf/user/create.proto", "message CreateI {}\nmessage CreateO {}")
+				mustCreateFile(fs, "pbf/user/README.md", "")
+
+				mustCreateFile(fs, "pbf/post/delete.proto", "package post;")
+
+				mustCreateFile(fs, ".git/foo.proto", "invalid")
+
+				return fs
+			}(),
+			src: ".",
+		},
+		// Case 1 ensures that only proto files in the source directory are
+		// scanned.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateFile(fs, "pbf/user/api.proto", "service API {}")
+				mustCreateFile(fs, "pbf/post/delete.proto", "invalid")
+
+				return fs
+			}(),
+			src: "./pbf/user/",
+		},
+		// Case 2 ensures that excluded directories and files are not scanned.
+		{
+			fs: func() afero.Fs {
+				fs := afero.NewMemMapFs()
+
+				mustCreateFile(fs, "pbf/user/api.proto", "service API {}")
+				mustCreateFile(fs, "pbf/user/legacy.proto", "invalid")
+				mustCreateFile(fs, "pbf/internal/debug.proto", "invalid")
+				mustCreateFile(fs, "pbf/post/legacy.proto", "invalid")
+
+				return fs
+			}(),
+			exc: []string{"pbf/internal", "pbf/*/legacy.proto"},
+			src: ".",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			var s *Schema
+			{
+				c := Config{
+					FileSystem: tc.fs,
+
+					Exclude: tc.exc,
+					Source:  tc.src,
+				}
+
+				s, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, err := s.Directories()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := json.MarshalIndent(l, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, '\n')
+
+			p := filepath.Join("testdata/directories", fileName(i))
+			if *update {
+				err := ioutil.WriteFile(p, actual, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Test_Schema_Fingerprint tests the fingerprints of sources. The tests here
+// ensure that fingerprints cover exactly the protocol buffer files scanned by
+// Directories and that they change whenever any of these files change.
+func Test_Schema_Fingerprint(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	mustCreateFile(fs, "pbf/user/api.proto", "service API {}")
+	mustCreateFile(fs, "pbf/user/README.md", "")
+	mustCreateFile(fs, "pbf/internal/debug.proto", "invalid")
+
+	var err error
+
+	var s *Schema
+	{
+		c := Config{
+			FileSystem: fs,
+
+			Exclude: []string{"pbf/internal"},
+			Source:  ".",
+		}
+
+		s, err = New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := s.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(a) != 1 || a["pbf/user/api.proto"] == "" {
+		t.Fatalf("expected fingerprint of pbf/user/api.proto only got %#v", a)
+	}
+
+	mustCreateFile(fs, "pbf/user/api.proto", "service API { rpc Search(SearchI) returns (SearchO); }")
+
+	b, err := s.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a["pbf/user/api.proto"] == b["pbf/user/api.proto"] {
+		t.Fatalf("expected fingerprint of pbf/user/api.proto to change")
+	}
+}
+
+func fileName(i int) string {
+	return "case-" + strconv.Itoa(i) + ".golden"
+}
+
+func mustCreateFile(fs afero.Fs, p string, s string) {
+	err := afero.WriteFile(fs, p, []byte(s), 0644)
+	if err != nil {
+		panic(err)
+	}
+}
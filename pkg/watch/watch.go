@@ -0,0 +1,151 @@
+// Package watch detects changes of files by polling their fingerprints, e.g.
+// in order to generate code again whenever protocol buffer files change.
+package watch
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/xh3b4sd/tracer"
+)
+
+type Config struct {
+	// Debounce is the duration files must not change anymore before changes
+	// are reported, so that editors saving multiple files at once only cause
+	// a single report.
+	Debounce time.Duration
+	// Fingerprint returns the current fingerprint of every watched file,
+	// e.g. its size and modification time, indexed by file path.
+	Fingerprint func() (map[string]string, error)
+	// Interval is the duration between two fingerprints.
+	Interval time.Duration
+}
+
+type Watcher struct {
+	debounce    time.Duration
+	fingerprint func() (map[string]string, error)
+	interval    time.Duration
+
+	last map[string]string
+}
+
+func New(config Config) (*Watcher, error) {
+	if config.Debounce == 0 {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Debounce must not be empty", config)
+	}
+	if config.Fingerprint == nil {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Fingerprint must not be empty", config)
+	}
+	if config.Interval == 0 {
+		return nil, tracer.Maskf(invalidConfigError, "%T.Interval must not be empty", config)
+	}
+
+	w := &Watcher{
+		debounce:    config.Debounce,
+		fingerprint: config.Fingerprint,
+		interval:    config.Interval,
+	}
+
+	return w, nil
+}
+
+// Snapshot remembers the current fingerprint, which subsequent calls to Wait
+// compare against.
+func (w *Watcher) Snapshot() error {
+	m, err := w.fingerprint()
+	if err != nil {
+		return tracer.Mask(err)
+	}
+
+	w.last = m
+
+	return nil
+}
+
+// Wait blocks until any file got created, changed or removed since the last
+// snapshot and returns the sorted paths of all of them. Changes are only
+// reported once files did not change anymore for the configured debounce
+// duration. The fingerprint at that point becomes the new snapshot.
+func (w *Watcher) Wait(ctx context.Context) ([]string, error) {
+	for {
+		err := sleep(ctx, w.interval)
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		cur, err := w.fingerprint()
+		if err != nil {
+			return nil, tracer.Mask(err)
+		}
+
+		if len(changes(w.last, cur)) == 0 {
+			continue
+		}
+
+		for {
+			err := sleep(ctx, w.debounce)
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			nxt, err := w.fingerprint()
+			if err != nil {
+				return nil, tracer.Mask(err)
+			}
+
+			if len(changes(cur, nxt)) == 0 {
+				break
+			}
+
+			cur = nxt
+		}
+
+		l := changes(w.last, cur)
+		w.last = cur
+
+		// Files may have been changed and changed back within the debounce
+		// duration, which does not need to be reported.
+		if len(l) == 0 {
+			continue
+		}
+
+		return l, nil
+	}
+}
+
+// changes returns the sorted paths of all files which differ between the two
+// given fingerprints.
+func changes(a map[string]string, b map[string]string) []string {
+	var l []string
+
+	for k, v := range a {
+		x, ok := b[k]
+		if !ok || x != v {
+			l = append(l, k)
+		}
+	}
+
+	for k := range b {
+		_, ok := a[k]
+		if !ok {
+			l = append(l, k)
+		}
+	}
+
+	sort.Strings(l)
+
+	return l
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return tracer.Mask(ctx.Err())
+	case <-t.C:
+		return nil
+	}
+}
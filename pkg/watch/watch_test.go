@@ -0,0 +1,102 @@
+package watch
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Test_Watch_Wait tests the detection of changed files. The tests here ensure
+// that created, changed and removed files are reported once they did not
+// change anymore and that changes reverted in the meantime are not reported
+// at all.
+func Test_Watch_Wait(t *testing.T) {
+	testCases := []struct {
+		fps []map[string]string
+		chg []string
+	}{
+		// Case 0 ensures that created, changed and removed files are reported.
+		{
+			fps: []map[string]string{
+				{"a.proto": "1", "b.proto": "1"},
+				{"a.proto": "1", "b.proto": "1"},
+				{"a.proto": "2", "c.proto": "1"},
+			},
+			chg: []string{"a.proto", "b.proto", "c.proto"},
+		},
+		// Case 1 ensures that changes are only reported once files did not
+		// change anymore.
+		{
+			fps: []map[string]string{
+				{"a.proto": "1"},
+				{"a.proto": "2"},
+				{"a.proto": "2", "b.proto": "1"},
+				{"a.proto": "2", "b.proto": "2"},
+			},
+			chg: []string{"a.proto", "b.proto"},
+		},
+		// Case 2 ensures that reverted changes are not reported.
+		{
+			fps: []map[string]string{
+				{"a.proto": "1"},
+				{"a.proto": "2"},
+				{"a.proto": "1"},
+				{"a.proto": "1"},
+				{"a.proto": "1", "b.proto": "1"},
+			},
+			chg: []string{"b.proto"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var err error
+
+			// The fingerprints are returned one after another. The last
+			// fingerprint is returned for all remaining calls.
+			var n int
+			f := func() (map[string]string, error) {
+				m := tc.fps[n]
+				if n < len(tc.fps)-1 {
+					n++
+				}
+
+				return m, nil
+			}
+
+			var w *Watcher
+			{
+				c := Config{
+					Debounce:    time.Millisecond,
+					Fingerprint: f,
+					Interval:    time.Millisecond,
+				}
+
+				w, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = w.Snapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			l, err := w.Wait(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(tc.chg, l) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.chg, l))
+			}
+		})
+	}
+}